@@ -0,0 +1,190 @@
+package containerpatterns
+
+import (
+	"fmt"
+	"strings"
+
+	ec2 "github.com/aws/aws-cdk-go/awscdk/v2/awsec2"
+	ecs "github.com/aws/aws-cdk-go/awscdk/v2/awsecs"
+	elbv2 "github.com/aws/aws-cdk-go/awscdk/v2/awselasticloadbalancingv2"
+	servicediscovery "github.com/aws/aws-cdk-go/awscdk/v2/awsservicediscovery"
+	ssm "github.com/aws/aws-cdk-go/awscdk/v2/awsssm"
+	"github.com/aws/constructs-go/constructs/v10"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// parameter names below the prefix
+const (
+	PARAMETER_CLUSTER_NAME                    string = "cluster-name"
+	PARAMETER_VPC_ID                          string = "vpc-id"
+	PARAMETER_HTTPS_LISTENER_ARN              string = "https-listener-arn"
+	PARAMETER_LOAD_BALANCER_SECURITY_GROUP_ID string = "load-balancer-security-group-id"
+	PARAMETER_ASG_SECURITY_GROUP_IDS          string = "asg-security-group-ids"
+	PARAMETER_CAPACITY_PROVIDER_NAMES         string = "capacity-provider-names"
+	PARAMETER_CLOUDMAP_NAMESPACE_NAME         string = "cloudmap-namespace-name"
+	PARAMETER_CLOUDMAP_NAMESPACE_ID           string = "cloudmap-namespace-id"
+	PARAMETER_CLOUDMAP_NAMESPACE_ARN          string = "cloudmap-namespace-arn"
+)
+
+// SSM does not store empty lists, an empty list is published as this single value
+const PARAMETER_EMPTY_LIST_VALUE string = "-"
+
+// ContainerComputeParametersProps publishes the compute's attributes as SSM parameters named Prefix/<name>, e.g.
+// /platform/prod/cluster-name. Clusters without ASG capacity providers publish empty security group and capacity
+// provider lists.
+type ContainerComputeParametersProps struct {
+	Prefix string
+}
+
+// ContainerComputeImportProps reads the parameters published under Prefix. The VPC ID and the ASG lists are looked
+// up when synthesizing and cached in cdk.context.json, the remaining attributes are resolved when deploying.
+type ContainerComputeImportProps struct {
+	Prefix string
+}
+
+// ImportedContainerCompute is a ContainerCompute from another app, it can be passed to a service as its Compute
+type ImportedContainerCompute interface {
+	constructs.Construct
+	ServiceCompute
+	LoadBalancerSecurityGroup() ec2.ISecurityGroup
+	AsgSecurityGroups() []ec2.ISecurityGroup
+	CapacityProviderNames() []string
+}
+
+type importedContainerCompute struct {
+	constructs.Construct
+	cluster                   ecs.ICluster
+	vpc                       ec2.IVpc
+	httpsListener             elbv2.IApplicationListener
+	cloudmapNamespace         servicediscovery.IPrivateDnsNamespace
+	loadBalancerSecurityGroup ec2.ISecurityGroup
+	asgSecurityGroups         []ec2.ISecurityGroup
+	capacityProviderNames     []string
+}
+
+func (c *importedContainerCompute) Cluster() ecs.ICluster {
+	return c.cluster
+}
+
+func (c *importedContainerCompute) Vpc() ec2.IVpc {
+	return c.vpc
+}
+
+func (c *importedContainerCompute) HttpsListener() elbv2.IApplicationListener {
+	return c.httpsListener
+}
+
+func (c *importedContainerCompute) CloudMapNamespace() servicediscovery.IPrivateDnsNamespace {
+	return c.cloudmapNamespace
+}
+
+func (c *importedContainerCompute) LoadBalancerSecurityGroup() ec2.ISecurityGroup {
+	return c.loadBalancerSecurityGroup
+}
+
+func (c *importedContainerCompute) AsgSecurityGroups() []ec2.ISecurityGroup {
+	return c.asgSecurityGroups
+}
+
+func (c *importedContainerCompute) CapacityProviderNames() []string {
+	return c.capacityProviderNames
+}
+
+func ContainerComputeFromParameters(scope constructs.Construct, id *string, props *ContainerComputeImportProps) ImportedContainerCompute {
+	this := constructs.NewConstruct(scope, id)
+	prefix := validateParameterPrefix(props.Prefix)
+
+	valueOf := func(name string) *string {
+		return ssm.StringParameter_ValueForStringParameter(this, jsii.String(prefix+"/"+name), nil)
+	}
+
+	vpc := ec2.Vpc_FromLookup(this, jsii.String("Vpc"), &ec2.VpcLookupOptions{
+		VpcId: ssm.StringParameter_ValueFromLookup(this, jsii.String(prefix+"/"+PARAMETER_VPC_ID)),
+	})
+
+	asgSecurityGroups := []ec2.ISecurityGroup{}
+	for index, securityGroupId := range lookupParameterList(this, prefix+"/"+PARAMETER_ASG_SECURITY_GROUP_IDS) {
+		asgSecurityGroups = append(asgSecurityGroups, ec2.SecurityGroup_FromSecurityGroupId(this, jsii.String(fmt.Sprintf("AsgSecurityGroup%d", index)), jsii.String(securityGroupId), &ec2.SecurityGroupImportOptions{}))
+	}
+	capacityProviderNames := lookupParameterList(this, prefix+"/"+PARAMETER_CAPACITY_PROVIDER_NAMES)
+
+	cluster := ecs.Cluster_FromClusterAttributes(this, jsii.String("Cluster"), &ecs.ClusterAttributes{
+		ClusterName:    valueOf(PARAMETER_CLUSTER_NAME),
+		Vpc:            vpc,
+		SecurityGroups: &asgSecurityGroups,
+	})
+
+	loadBalancerSecurityGroup := ec2.SecurityGroup_FromSecurityGroupId(this, jsii.String("LoadBalancerSecurityGroup"), valueOf(PARAMETER_LOAD_BALANCER_SECURITY_GROUP_ID), &ec2.SecurityGroupImportOptions{})
+	httpsListener := elbv2.ApplicationListener_FromApplicationListenerAttributes(this, jsii.String("HttpsListener"), &elbv2.ApplicationListenerAttributes{
+		ListenerArn:   valueOf(PARAMETER_HTTPS_LISTENER_ARN),
+		SecurityGroup: loadBalancerSecurityGroup,
+	})
+
+	cloudmapNamespace := servicediscovery.PrivateDnsNamespace_FromPrivateDnsNamespaceAttributes(this, jsii.String("CloudMapNamespace"), &servicediscovery.PrivateDnsNamespaceAttributes{
+		NamespaceName: valueOf(PARAMETER_CLOUDMAP_NAMESPACE_NAME),
+		NamespaceId:   valueOf(PARAMETER_CLOUDMAP_NAMESPACE_ID),
+		NamespaceArn:  valueOf(PARAMETER_CLOUDMAP_NAMESPACE_ARN),
+	})
+
+	return &importedContainerCompute{this, cluster, vpc, httpsListener, cloudmapNamespace, loadBalancerSecurityGroup, asgSecurityGroups, capacityProviderNames}
+}
+
+// createContainerComputeParameters publishes the attributes ContainerComputeFromParameters reads back
+func createContainerComputeParameters(scope constructs.Construct, id *string, props *ContainerComputeParametersProps, compute ContainerCompute, asgSecurityGroups []ec2.ISecurityGroup, capacityProviderNames []*string) {
+	this := constructs.NewConstruct(scope, id)
+	prefix := validateParameterPrefix(props.Prefix)
+
+	parameters := []struct {
+		name  string
+		value *string
+	}{
+		{PARAMETER_CLUSTER_NAME, compute.Cluster().ClusterName()},
+		{PARAMETER_VPC_ID, compute.Vpc().VpcId()},
+		{PARAMETER_HTTPS_LISTENER_ARN, compute.HttpsListener().ListenerArn()},
+		{PARAMETER_LOAD_BALANCER_SECURITY_GROUP_ID, (*compute.LoadBalancer().Connections().SecurityGroups())[0].SecurityGroupId()},
+		{PARAMETER_CLOUDMAP_NAMESPACE_NAME, compute.CloudMapNamespace().NamespaceName()},
+		{PARAMETER_CLOUDMAP_NAMESPACE_ID, compute.CloudMapNamespace().NamespaceId()},
+		{PARAMETER_CLOUDMAP_NAMESPACE_ARN, compute.CloudMapNamespace().NamespaceArn()},
+	}
+	for _, parameter := range parameters {
+		ssm.NewStringParameter(this, jsii.String(parameter.name), &ssm.StringParameterProps{
+			ParameterName: jsii.String(prefix + "/" + parameter.name),
+			StringValue:   parameter.value,
+		})
+	}
+
+	securityGroupIds := []*string{}
+	for _, securityGroup := range asgSecurityGroups {
+		securityGroupIds = append(securityGroupIds, securityGroup.SecurityGroupId())
+	}
+	if len(securityGroupIds) == 0 {
+		securityGroupIds = append(securityGroupIds, jsii.String(PARAMETER_EMPTY_LIST_VALUE))
+	}
+	if len(capacityProviderNames) == 0 {
+		capacityProviderNames = append(capacityProviderNames, jsii.String(PARAMETER_EMPTY_LIST_VALUE))
+	}
+	ssm.NewStringListParameter(this, jsii.String(PARAMETER_ASG_SECURITY_GROUP_IDS), &ssm.StringListParameterProps{
+		ParameterName:   jsii.String(prefix + "/" + PARAMETER_ASG_SECURITY_GROUP_IDS),
+		StringListValue: &securityGroupIds,
+	})
+	ssm.NewStringListParameter(this, jsii.String(PARAMETER_CAPACITY_PROVIDER_NAMES), &ssm.StringListParameterProps{
+		ParameterName:   jsii.String(prefix + "/" + PARAMETER_CAPACITY_PROVIDER_NAMES),
+		StringListValue: &capacityProviderNames,
+	})
+}
+
+func validateParameterPrefix(prefix string) string {
+	if !strings.HasPrefix(prefix, "/") || len(prefix) < 2 {
+		panic("parameter prefix must start with / and name at least one level, e.g. /platform/prod")
+	}
+	return strings.TrimSuffix(prefix, "/")
+}
+
+// lookupParameterList reads a StringList parameter when synthesizing, so its values can be used one by one
+func lookupParameterList(scope constructs.Construct, name string) []string {
+	value := *ssm.StringParameter_ValueFromLookup(scope, jsii.String(name))
+	if value == PARAMETER_EMPTY_LIST_VALUE {
+		return []string{}
+	}
+	return strings.Split(value, ",")
+}
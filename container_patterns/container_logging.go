@@ -0,0 +1,255 @@
+package containerpatterns
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	ecs "github.com/aws/aws-cdk-go/awscdk/v2/awsecs"
+	iam "github.com/aws/aws-cdk-go/awscdk/v2/awsiam"
+	kms "github.com/aws/aws-cdk-go/awscdk/v2/awskms"
+	cloudwatchlogs "github.com/aws/aws-cdk-go/awscdk/v2/awslogs"
+	s3 "github.com/aws/aws-cdk-go/awscdk/v2/awss3"
+	"github.com/aws/constructs-go/constructs/v10"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// custom types
+type (
+	LogDriver           string
+	FireLensDestination string
+)
+
+const (
+	LOG_DRIVER_AWS_LOGS LogDriver = "AWS_LOGS"
+	LOG_DRIVER_FIRELENS LogDriver = "FIRELENS"
+	DEFAULT_LOG_DRIVER  LogDriver = LOG_DRIVER_AWS_LOGS
+)
+
+const (
+	FIRELENS_DESTINATION_CLOUDWATCH_LOGS  FireLensDestination = "CLOUDWATCH_LOGS"
+	FIRELENS_DESTINATION_S3               FireLensDestination = "S3"
+	FIRELENS_DESTINATION_OPENSEARCH       FireLensDestination = "OPENSEARCH"
+	FIRELENS_DESTINATION_KINESIS_FIREHOSE FireLensDestination = "KINESIS_FIREHOSE"
+	DEFAULT_FIRELENS_DESTINATION          FireLensDestination = FIRELENS_DESTINATION_CLOUDWATCH_LOGS
+)
+
+const (
+	DEFAULT_FLUENT_BIT_IMAGE          string  = "public.ecr.aws/aws-observability/aws-for-fluent-bit:stable"
+	FIRELENS_LOG_ROUTER_CONTAINER     string  = "log-router"
+	FIRELENS_LOG_ROUTER_MEMORY_MIB    float64 = 50
+	DEFAULT_FIRELENS_S3_KEY_PREFIX    string  = "logs"
+	DEFAULT_FIRELENS_OPENSEARCH_INDEX string  = "ecs-logs"
+)
+
+// LoggingProps configures where container logs go. On the service it applies to every application container; on a
+// ContainerDefinition it replaces the service configuration for that container once Driver is set.
+//
+// LogGroupName creates a log group with the given Retention, KmsKeyArn and removal behaviour, ExistingLogGroupName
+// imports one instead. A container without its own log group writes to the service log group.
+type LoggingProps struct {
+	Driver               LogDriver
+	LogGroupName         string
+	ExistingLogGroupName string
+	Retention            cloudwatchlogs.RetentionDays
+	KmsKeyArn            string
+	IsRetainOnDelete     bool
+	StreamPrefix         string
+	MultilinePattern     string
+	DatetimeFormat       string
+	FireLens             FireLensProps
+}
+
+// FireLensProps configures the Fluent Bit output used by containers logging through FireLens. The CloudWatch Logs
+// destination writes to the container's log group.
+type FireLensProps struct {
+	Destination         FireLensDestination
+	FluentBitImage      string
+	BucketName          string
+	BucketArn           string
+	S3KeyPrefix         string
+	OpenSearchEndpoint  string
+	OpenSearchDomainArn string
+	OpenSearchIndex     string
+	DeliveryStreamName  string
+}
+
+func createServiceLogGroup(scope constructs.Construct, id *string, name string, props *LoggingProps) cloudwatchlogs.ILogGroup {
+	if props.ExistingLogGroupName != "" {
+		return cloudwatchlogs.LogGroup_FromLogGroupName(scope, id, jsii.String(props.ExistingLogGroupName))
+	}
+	if props.LogGroupName != "" {
+		name = props.LogGroupName
+	}
+
+	retention := props.Retention
+	if retention == "" {
+		retention = DEFAULT_LOG_RETENTION
+	}
+	removalPolicy := awscdk.RemovalPolicy_DESTROY
+	if props.IsRetainOnDelete {
+		removalPolicy = awscdk.RemovalPolicy_RETAIN
+	}
+	var encryptionKey kms.IKey = nil
+	if props.KmsKeyArn != "" {
+		encryptionKey = kms.Key_FromKeyArn(scope, jsii.String(*id+"EncryptionKey"), jsii.String(props.KmsKeyArn))
+	}
+
+	logGroup := cloudwatchlogs.NewLogGroup(scope, id, &cloudwatchlogs.LogGroupProps{
+		LogGroupName:  optionalString(name),
+		Retention:     retention,
+		EncryptionKey: encryptionKey,
+		RemovalPolicy: removalPolicy,
+	})
+	return logGroup
+}
+
+// resolveContainerLogging returns the logging configuration and log group for an application container,
+// creating the container's own log group when it names one.
+func resolveContainerLogging(scope constructs.Construct, id string, containerDef *ContainerDefinition, serviceLogging *LoggingProps, serviceLogGroup cloudwatchlogs.ILogGroup) (*LoggingProps, cloudwatchlogs.ILogGroup) {
+	if containerDef.Logging.Driver == "" {
+		return serviceLogging, serviceLogGroup
+	}
+	logging := &containerDef.Logging
+	if logging.LogGroupName == "" && logging.ExistingLogGroupName == "" {
+		return logging, serviceLogGroup
+	}
+	return logging, createServiceLogGroup(scope, jsii.String(id+"LogGroup"), "", logging)
+}
+
+func createContainerLogDriver(scope constructs.Construct, id string, containerName string, props *LoggingProps, logGroup cloudwatchlogs.ILogGroup, taskDef ecs.TaskDefinition) ecs.LogDriver {
+	streamPrefix := props.StreamPrefix
+	if streamPrefix == "" {
+		streamPrefix = containerName
+	}
+
+	switch props.Driver {
+	case "", LOG_DRIVER_AWS_LOGS:
+		return ecs.AwsLogDriver_AwsLogs(&ecs.AwsLogDriverProps{
+			LogGroup:         logGroup,
+			StreamPrefix:     jsii.String(streamPrefix),
+			MultilinePattern: optionalString(props.MultilinePattern),
+			DatetimeFormat:   optionalString(props.DatetimeFormat),
+		})
+	case LOG_DRIVER_FIRELENS:
+		return ecs.LogDrivers_Firelens(&ecs.FireLensLogDriverProps{
+			Options: createFireLensOutputOptions(scope, id, containerName, streamPrefix, &props.FireLens, logGroup, taskDef),
+		})
+	default:
+		panic(fmt.Sprintf("invalid log driver %q for container %s", props.Driver, containerName))
+	}
+}
+
+// createFireLensOutputOptions renders the Fluent Bit output plugin options for the destination and grants the task
+// role write access to it.
+func createFireLensOutputOptions(scope constructs.Construct, id string, containerName string, streamPrefix string, props *FireLensProps, logGroup cloudwatchlogs.ILogGroup, taskDef ecs.TaskDefinition) *map[string]*string {
+	switch props.Destination {
+	case "", FIRELENS_DESTINATION_CLOUDWATCH_LOGS:
+		logGroup.GrantWrite(taskDef.TaskRole())
+		return &map[string]*string{
+			"Name":              jsii.String("cloudwatch_logs"),
+			"region":            awscdk.Aws_REGION(),
+			"log_group_name":    logGroup.LogGroupName(),
+			"log_stream_prefix": jsii.String(streamPrefix + "/"),
+		}
+	case FIRELENS_DESTINATION_S3:
+		bucket := lookupFireLensBucket(scope, id+"FireLensBucket", containerName, props)
+		keyPrefix := props.S3KeyPrefix
+		if keyPrefix == "" {
+			keyPrefix = DEFAULT_FIRELENS_S3_KEY_PREFIX
+		}
+		bucket.GrantPut(taskDef.TaskRole(), jsii.String(keyPrefix+"/*"))
+		return &map[string]*string{
+			"Name":            jsii.String("s3"),
+			"region":          awscdk.Aws_REGION(),
+			"bucket":          bucket.BucketName(),
+			"total_file_size": jsii.String("1M"),
+			"upload_timeout":  jsii.String("1m"),
+			"use_put_object":  jsii.String("On"),
+			"s3_key_format":   jsii.String("/" + keyPrefix + "/" + streamPrefix + "/%Y/%m/%d/%H/%M/%S-$UUID"),
+		}
+	case FIRELENS_DESTINATION_OPENSEARCH:
+		if props.OpenSearchEndpoint == "" || props.OpenSearchDomainArn == "" {
+			panic("FireLens OpenSearch destination of container " + containerName + " requires OpenSearchEndpoint and OpenSearchDomainArn")
+		}
+		index := props.OpenSearchIndex
+		if index == "" {
+			index = DEFAULT_FIRELENS_OPENSEARCH_INDEX
+		}
+		taskDef.AddToTaskRolePolicy(iam.NewPolicyStatement(&iam.PolicyStatementProps{
+			Effect: iam.Effect_ALLOW,
+			Actions: &[]*string{
+				jsii.String("es:ESHttpPost"),
+				jsii.String("es:ESHttpPut"),
+			},
+			Resources: &[]*string{
+				jsii.String(props.OpenSearchDomainArn + "/*"),
+			},
+		}))
+		return &map[string]*string{
+			"Name":               jsii.String("opensearch"),
+			"Host":               jsii.String(props.OpenSearchEndpoint),
+			"Port":               jsii.String("443"),
+			"Index":              jsii.String(index),
+			"AWS_Auth":           jsii.String("On"),
+			"AWS_Region":         awscdk.Aws_REGION(),
+			"tls":                jsii.String("On"),
+			"Suppress_Type_Name": jsii.String("On"),
+			"Trace_Error":        jsii.String("On"),
+		}
+	case FIRELENS_DESTINATION_KINESIS_FIREHOSE:
+		if props.DeliveryStreamName == "" {
+			panic("FireLens Kinesis Firehose destination of container " + containerName + " requires DeliveryStreamName")
+		}
+		taskDef.AddToTaskRolePolicy(iam.NewPolicyStatement(&iam.PolicyStatementProps{
+			Effect: iam.Effect_ALLOW,
+			Actions: &[]*string{
+				jsii.String("firehose:PutRecordBatch"),
+			},
+			Resources: &[]*string{
+				awscdk.Arn_Format(&awscdk.ArnComponents{
+					Service:      jsii.String("firehose"),
+					Resource:     jsii.String("deliverystream"),
+					ResourceName: jsii.String(props.DeliveryStreamName),
+				}, awscdk.Stack_Of(taskDef)),
+			},
+		}))
+		return &map[string]*string{
+			"Name":            jsii.String("kinesis_firehose"),
+			"region":          awscdk.Aws_REGION(),
+			"delivery_stream": jsii.String(props.DeliveryStreamName),
+		}
+	default:
+		panic(fmt.Sprintf("invalid FireLens destination %q for container %s", props.Destination, containerName))
+	}
+}
+
+func lookupFireLensBucket(scope constructs.Construct, id string, containerName string, props *FireLensProps) s3.IBucket {
+	if props.BucketArn != "" {
+		return s3.Bucket_FromBucketArn(scope, jsii.String(id), jsii.String(props.BucketArn))
+	}
+	if props.BucketName != "" {
+		return s3.Bucket_FromBucketName(scope, jsii.String(id), jsii.String(props.BucketName))
+	}
+	panic("FireLens S3 destination of container " + containerName + " requires BucketArn or BucketName")
+}
+
+// createFireLensLogRouter adds the Fluent Bit sidecar that FireLens routes container logs through, its own logs go to
+// the service log group.
+func createFireLensLogRouter(props *FireLensProps, taskDef ecs.TaskDefinition, logGroup cloudwatchlogs.ILogGroup) ecs.FirelensLogRouter {
+	image := props.FluentBitImage
+	if image == "" {
+		image = DEFAULT_FLUENT_BIT_IMAGE
+	}
+
+	logRouter := taskDef.AddFirelensLogRouter(jsii.String("FireLensLogRouter"), &ecs.FirelensLogRouterDefinitionOptions{
+		ContainerName: jsii.String(FIRELENS_LOG_ROUTER_CONTAINER),
+		Image:         ecs.ContainerImage_FromRegistry(jsii.String(image), &ecs.RepositoryImageProps{}),
+		FirelensConfig: &ecs.FirelensConfig{
+			Type: ecs.FirelensLogRouterType_FLUENTBIT,
+		},
+		Essential:            jsii.Bool(true),
+		MemoryReservationMiB: jsii.Number(FIRELENS_LOG_ROUTER_MEMORY_MIB),
+		Logging:              setupContianerAwsLogDriver(logGroup, "FireLens"),
+	})
+	return logRouter
+}
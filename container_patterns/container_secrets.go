@@ -0,0 +1,131 @@
+package containerpatterns
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	ecs "github.com/aws/aws-cdk-go/awscdk/v2/awsecs"
+	iam "github.com/aws/aws-cdk-go/awscdk/v2/awsiam"
+	secretsmanager "github.com/aws/aws-cdk-go/awscdk/v2/awssecretsmanager"
+	ssm "github.com/aws/aws-cdk-go/awscdk/v2/awsssm"
+	"github.com/aws/constructs-go/constructs/v10"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// custom types
+type ContainerSecretSource string
+
+const (
+	CONTAINER_SECRET_SOURCE_SECRETS_MANAGER ContainerSecretSource = "SECRETS_MANAGER"
+	CONTAINER_SECRET_SOURCE_SSM_PARAMETER   ContainerSecretSource = "SSM_PARAMETER"
+)
+
+// ContainerSecret exposes a Secrets Manager secret (or one JSON key of it) or an SSM parameter
+// to the container as the environment variable EnvironmentVariable. SecretArn may be the complete ARN
+// or the partial ARN without the random suffix Secrets Manager appends to the secret name.
+type ContainerSecret struct {
+	EnvironmentVariable string
+	Source              ContainerSecretSource
+	SecretArn           string
+	SecretName          string
+	SecretJsonKey       string
+	ParameterName       string
+	KmsKeyArn           string
+}
+
+// createContainerSecrets converts the container secrets to ecs.Secret values. ecs.ContainerDefinition grants the
+// task execution role read access to each of them; kms:Decrypt on customer managed keys is added here.
+func createContainerSecrets(scope constructs.Construct, containerName string, secrets []ContainerSecret, taskDef ecs.TaskDefinition) *map[string]ecs.Secret {
+	if len(secrets) == 0 {
+		return nil
+	}
+
+	containerSecrets := map[string]ecs.Secret{}
+	for index, secret := range secrets {
+		id := containerName + "Secret" + strconv.FormatInt(int64(index), 10)
+		if secret.EnvironmentVariable == "" {
+			panic(fmt.Sprintf("secret %d of container %s requires EnvironmentVariable", index, containerName))
+		}
+		if _, exists := containerSecrets[secret.EnvironmentVariable]; exists {
+			panic("container " + containerName + " sets the secret " + secret.EnvironmentVariable + " more than once")
+		}
+
+		switch secret.Source {
+		case CONTAINER_SECRET_SOURCE_SECRETS_MANAGER:
+			containerSecrets[secret.EnvironmentVariable] = ecs.Secret_FromSecretsManager(
+				lookupSecretsManagerSecret(scope, id, &secret),
+				optionalString(secret.SecretJsonKey),
+			)
+		case CONTAINER_SECRET_SOURCE_SSM_PARAMETER:
+			if secret.ParameterName == "" {
+				panic("container secret " + secret.EnvironmentVariable + " requires ParameterName")
+			}
+			containerSecrets[secret.EnvironmentVariable] = ecs.Secret_FromSsmParameter(
+				ssm.StringParameter_FromSecureStringParameterAttributes(scope, jsii.String(id), &ssm.SecureStringParameterAttributes{
+					ParameterName: jsii.String(secret.ParameterName),
+				}),
+			)
+		default:
+			panic(fmt.Sprintf("invalid source %q for container secret %s", secret.Source, secret.EnvironmentVariable))
+		}
+
+		if secret.KmsKeyArn != "" {
+			taskDef.AddToExecutionRolePolicy(createSecretDecryptPolicyStatement(secret.Source, secret.KmsKeyArn))
+		}
+	}
+	return &containerSecrets
+}
+
+func createSecretDecryptPolicyStatement(source ContainerSecretSource, kmsKeyArn string) iam.PolicyStatement {
+	viaService := "secretsmanager"
+	if source == CONTAINER_SECRET_SOURCE_SSM_PARAMETER {
+		viaService = "ssm"
+	}
+
+	policy := iam.NewPolicyStatement(&iam.PolicyStatementProps{
+		Effect: iam.Effect_ALLOW,
+		Actions: &[]*string{
+			jsii.String("kms:Decrypt"),
+		},
+		Resources: &[]*string{
+			jsii.String(kmsKeyArn),
+		},
+		Conditions: &map[string]interface{}{
+			"StringEquals": map[string]interface{}{
+				"kms:ViaService": viaService + "." + *awscdk.Aws_REGION() + ".amazonaws.com",
+			},
+		},
+	})
+
+	return policy
+}
+
+func lookupSecretsManagerSecret(scope constructs.Construct, id string, secret *ContainerSecret) secretsmanager.ISecret {
+	if secret.SecretArn != "" {
+		if isCompleteSecretArn(secret.SecretArn) {
+			return secretsmanager.Secret_FromSecretCompleteArn(scope, jsii.String(id), jsii.String(secret.SecretArn))
+		}
+		return secretsmanager.Secret_FromSecretPartialArn(scope, jsii.String(id), jsii.String(secret.SecretArn))
+	}
+	if secret.SecretName != "" {
+		return secretsmanager.Secret_FromSecretNameV2(scope, jsii.String(id), jsii.String(secret.SecretName))
+	}
+	panic("container secret " + secret.EnvironmentVariable + " requires SecretArn or SecretName")
+}
+
+// complete secret ARNs end in the name, a hyphen and six random characters
+var completeSecretArnSuffix = regexp.MustCompile(`:secret:.+-[a-zA-Z0-9]{6}$`)
+
+// isCompleteSecretArn treats unresolved ARNs as complete, they usually reference a secret's ARN attribute
+func isCompleteSecretArn(arn string) bool {
+	return *awscdk.Token_IsUnresolved(jsii.String(arn)) || completeSecretArnSuffix.MatchString(arn)
+}
+
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return jsii.String(s)
+}
@@ -0,0 +1,68 @@
+package containerpatterns
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2/assertions"
+	ecs "github.com/aws/aws-cdk-go/awscdk/v2/awsecs"
+	"github.com/aws/jsii-runtime-go"
+)
+
+func TestPartialSecretArnIsMatchedByPrefix(t *testing.T) {
+	stack := newTestStack()
+	taskDef := ecs.NewFargateTaskDefinition(stack, jsii.String("TaskDefinition"), &ecs.FargateTaskDefinitionProps{})
+	taskDef.AddContainer(jsii.String("app"), &ecs.ContainerDefinitionOptions{
+		Image: ecs.ContainerImage_FromRegistry(jsii.String("nginx"), nil),
+		Secrets: createContainerSecrets(stack, "app", []ContainerSecret{
+			{EnvironmentVariable: "DB_PASSWORD", Source: CONTAINER_SECRET_SOURCE_SECRETS_MANAGER, SecretArn: "arn:aws:secretsmanager:us-east-1:123456789012:secret:db"},
+			{EnvironmentVariable: "API_KEY", Source: CONTAINER_SECRET_SOURCE_SECRETS_MANAGER, SecretArn: "arn:aws:secretsmanager:us-east-1:123456789012:secret:api-AbCdEf"},
+		}, taskDef),
+	})
+
+	assertions.Template_FromStack(stack, nil).HasResourceProperties(jsii.String("AWS::IAM::Policy"), map[string]interface{}{
+		"PolicyDocument": map[string]interface{}{
+			"Statement": assertions.Match_ArrayWith(&[]interface{}{
+				map[string]interface{}{
+					"Action":   []interface{}{"secretsmanager:GetSecretValue", "secretsmanager:DescribeSecret"},
+					"Effect":   "Allow",
+					"Resource": "arn:aws:secretsmanager:us-east-1:123456789012:secret:api-AbCdEf",
+				},
+				map[string]interface{}{
+					"Action":   []interface{}{"secretsmanager:GetSecretValue", "secretsmanager:DescribeSecret"},
+					"Effect":   "Allow",
+					"Resource": "arn:aws:secretsmanager:us-east-1:123456789012:secret:db-??????",
+				},
+			}),
+		},
+	})
+}
+
+func TestInvalidSecretEnvironmentVariablesPanic(t *testing.T) {
+	secret := ContainerSecret{EnvironmentVariable: "DB_PASSWORD", Source: CONTAINER_SECRET_SOURCE_SSM_PARAMETER, ParameterName: "/db/password"}
+	unnamed := secret
+	unnamed.EnvironmentVariable = ""
+
+	tests := []struct {
+		name    string
+		secrets []ContainerSecret
+		message string
+	}{
+		{"empty", []ContainerSecret{unnamed}, "requires EnvironmentVariable"},
+		{"duplicate", []ContainerSecret{secret, secret}, "sets the secret DB_PASSWORD more than once"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stack := newTestStack()
+			taskDef := ecs.NewFargateTaskDefinition(stack, jsii.String("TaskDefinition"), &ecs.FargateTaskDefinitionProps{})
+
+			defer func() {
+				if r := recover(); r == nil || !strings.Contains(fmt.Sprint(r), test.message) {
+					t.Fatalf("expected a panic containing %q, got %v", test.message, r)
+				}
+			}()
+			createContainerSecrets(stack, "app", test.secrets, taskDef)
+		})
+	}
+}
@@ -1,6 +1,8 @@
 package containerpatterns
 
 import (
+	"fmt"
+
 	"github.com/Breezeware-Technologies/breezeware-aws-cdk-patterns/network"
 	"github.com/aws/aws-cdk-go/awscdk/v2"
 	autoscaling "github.com/aws/aws-cdk-go/awscdk/v2/awsautoscaling"
@@ -8,12 +10,54 @@ import (
 	ecs "github.com/aws/aws-cdk-go/awscdk/v2/awsecs"
 	elbv2 "github.com/aws/aws-cdk-go/awscdk/v2/awselasticloadbalancingv2"
 	iam "github.com/aws/aws-cdk-go/awscdk/v2/awsiam"
+	kms "github.com/aws/aws-cdk-go/awscdk/v2/awskms"
 	servicediscovery "github.com/aws/aws-cdk-go/awscdk/v2/awsservicediscovery"
 	"github.com/aws/constructs-go/constructs/v10"
 	"github.com/aws/jsii-runtime-go"
 )
 
-var vpc ec2.IVpc
+// custom types
+type MachineImageType string
+
+const (
+	MACHINE_IMAGE_TYPE_AMAZON_LINUX_2              MachineImageType = "AMAZON_LINUX_2"
+	MACHINE_IMAGE_TYPE_ECS_AMAZON_LINUX_2          MachineImageType = "ECS_AMAZON_LINUX_2"
+	MACHINE_IMAGE_TYPE_ECS_AMAZON_LINUX_2_ARM64    MachineImageType = "ECS_AMAZON_LINUX_2_ARM64"
+	MACHINE_IMAGE_TYPE_ECS_AMAZON_LINUX_2023       MachineImageType = "ECS_AMAZON_LINUX_2023"
+	MACHINE_IMAGE_TYPE_ECS_AMAZON_LINUX_2023_ARM64 MachineImageType = "ECS_AMAZON_LINUX_2023_ARM64"
+	MACHINE_IMAGE_TYPE_BOTTLEROCKET                MachineImageType = "BOTTLEROCKET"
+	MACHINE_IMAGE_TYPE_BOTTLEROCKET_ARM64          MachineImageType = "BOTTLEROCKET_ARM64"
+	DEFAULT_MACHINE_IMAGE_TYPE                     MachineImageType = MACHINE_IMAGE_TYPE_AMAZON_LINUX_2
+)
+
+type LoadBalancerScheme string
+
+const (
+	LOAD_BALANCER_SCHEME_INTERNET_FACING LoadBalancerScheme = "INTERNET_FACING"
+	LOAD_BALANCER_SCHEME_INTERNAL        LoadBalancerScheme = "INTERNAL"
+	DEFAULT_LOAD_BALANCER_SCHEME         LoadBalancerScheme = LOAD_BALANCER_SCHEME_INTERNET_FACING
+)
+
+// constants
+const (
+	DEFAULT_LOAD_BALANCER_IDLE_TIMEOUT_SECONDS float64             = 120
+	DEFAULT_LOAD_BALANCER_IP_ADDRESS_TYPE      elbv2.IpAddressType = elbv2.IpAddressType_IPV4
+	DEFAULT_INTERNAL_LOAD_BALANCER_SUBNET_TYPE ec2.SubnetType      = ec2.SubnetType_PRIVATE_WITH_EGRESS
+)
+
+const (
+	DEFAULT_SPOT_ALLOCATION_STRATEGY          autoscaling.SpotAllocationStrategy = autoscaling.SpotAllocationStrategy_CAPACITY_OPTIMIZED
+	DEFAULT_ROOT_VOLUME_SIZE_GIB              float64                            = 30
+	DEFAULT_ROOT_VOLUME_TYPE                  ec2.EbsDeviceVolumeType            = ec2.EbsDeviceVolumeType_GP3
+	DEFAULT_ROOT_VOLUME_DEVICE_NAME           string                             = "/dev/xvda"
+	BOTTLEROCKET_DATA_VOLUME_DEVICE_NAME      string                             = "/dev/xvdb"
+	DEFAULT_TARGET_CAPACITY_PERCENT           float64                            = 100
+	DEFAULT_INSTANCE_DRAIN_TIME_SECONDS       float64                            = 300
+	DEFAULT_ASG_SUBNET_TYPE                   ec2.SubnetType                     = ec2.SubnetType_PRIVATE_WITH_EGRESS
+	DEFAULT_INSTANCE_METADATA_HOP_LIMIT       float64                            = 2
+	ECS_AMAZON_LINUX_2023_AMI_PARAMETER       string                             = "/aws/service/ecs/optimized-ami/amazon-linux-2023/recommended/image_id"
+	ECS_AMAZON_LINUX_2023_ARM64_AMI_PARAMETER string                             = "/aws/service/ecs/optimized-ami/amazon-linux-2023/arm64/recommended/image_id"
+)
 
 type ContainerCompute interface {
 	constructs.Construct
@@ -21,14 +65,20 @@ type ContainerCompute interface {
 	LoadBalancer() elbv2.IApplicationLoadBalancer
 	CloudMapNamespace() servicediscovery.IPrivateDnsNamespace
 	HttpsListener() elbv2.IApplicationListener
+	Vpc() ec2.IVpc
+	NetworkLoadBalancer() elbv2.INetworkLoadBalancer
+	NetworkTargetGroups() map[string]elbv2.INetworkTargetGroup
 }
 
 type containerCompute struct {
 	constructs.Construct
-	cluster           ecs.ICluster
-	loadbalancer      elbv2.IApplicationLoadBalancer
-	cloudmapNamespace servicediscovery.IPrivateDnsNamespace
-	httpsListener     elbv2.IApplicationListener
+	cluster             ecs.ICluster
+	loadbalancer        elbv2.IApplicationLoadBalancer
+	cloudmapNamespace   servicediscovery.IPrivateDnsNamespace
+	httpsListener       elbv2.IApplicationListener
+	vpc                 ec2.IVpc
+	networkLoadBalancer elbv2.INetworkLoadBalancer
+	networkTargetGroups map[string]elbv2.INetworkTargetGroup
 }
 
 type ContainerComputeClusterProps struct {
@@ -36,40 +86,95 @@ type ContainerComputeClusterProps struct {
 	ContainerInsights                bool
 	IsAsgCapacityProviderEnabled     bool
 	IsFargateCapacityProviderEnabled bool
-	vpc                              ec2.IVpc
 }
 
 type ContainerComputeAsgProps struct {
-	Name            string
-	MinCapacity     float64
-	MaxCapacity     float64
-	DesiredCapacity float64
-	SshKeyName      string
-	InstanceClass   ec2.InstanceClass
-	InstanceSize    ec2.InstanceSize
-	vpc             ec2.IVpc
+	Name                          string
+	MinCapacity                   float64
+	MaxCapacity                   float64
+	DesiredCapacity               float64
+	SshKeyName                    string
+	InstanceClass                 ec2.InstanceClass
+	InstanceSize                  ec2.InstanceSize
+	MachineImage                  MachineImageType
+	IsMixedInstancesPolicyEnabled bool
+	MixedInstancesPolicy          ContainerComputeMixedInstancesProps
+	RootVolume                    ContainerComputeRootVolumeProps
+	InstanceMetadata              ContainerComputeInstanceMetadataProps
+	IsDetailedMonitoringEnabled   bool
+	SubnetType                    ec2.SubnetType
+	SubnetIds                     []string
+	IsSsmAccessEnabled            bool
+	IsRexrayPluginEnabled         bool
 }
 
+// ContainerComputeRootVolumeProps sizes the encrypted EBS volume holding images and containers. Without a KmsKeyArn
+// the account's default EBS key is used; a customer managed key must allow the Auto Scaling service-linked role.
+type ContainerComputeRootVolumeProps struct {
+	SizeGiB    float64
+	VolumeType ec2.EbsDeviceVolumeType
+	Iops       float64
+	KmsKeyArn  string
+}
+
+// ContainerComputeInstanceMetadataProps tunes the IMDSv2 endpoint, a hop limit of 2 lets bridge mode containers
+// reach it through the docker bridge.
+type ContainerComputeInstanceMetadataProps struct {
+	HttpPutResponseHopLimit float64
+	IsInstanceTagsEnabled   bool
+}
+
+// ContainerComputeMixedInstancesProps runs the group on a mix of On-Demand and Spot instances. InstanceTypes
+// (e.g. "m5.large") replace InstanceClass and InstanceSize, capacity above OnDemandBaseCapacity is split by
+// OnDemandPercentageAboveBaseCapacity, so a zero value runs everything above the base on Spot.
+type ContainerComputeMixedInstancesProps struct {
+	InstanceTypes                       []string
+	OnDemandBaseCapacity                float64
+	OnDemandPercentageAboveBaseCapacity float64
+	SpotAllocationStrategy              autoscaling.SpotAllocationStrategy
+	SpotMaxPrice                        string
+}
+
+// ContainerComputeAsgCapacityProviderProps configures how ECS scales the group. Without managed termination protection
+// the cluster attaches a termination lifecycle hook whose Lambda (notified through SNS) sets the container instance to
+// DRAINING and holds the termination until its tasks have moved, for at most DEFAULT_INSTANCE_DRAIN_TIME_SECONDS.
+// Managed termination protection forces the drain time to 0, so no hook is attached and ECS only scales in
+// instances without running tasks.
 type ContainerComputeAsgCapacityProviderProps struct {
-	Name string
+	Name                                  string
+	IsManagedTerminationProtectionEnabled bool
+	TargetCapacityPercent                 float64
+	MinimumScalingStepSize                float64
+	MaximumScalingStepSize                float64
 }
 
+// ContainerComputeLoadBalancerProps configures the shared ALB. Internal load balancers only accept traffic from within
+// the VPC and default to private subnets, dual-stack ones also accept IPv6 clients when internet-facing. IngressPeers
+// replace these defaults, e.g. to let a peered VPC or an on-premises range reach an internal load balancer. Zero values
+// keep HTTP/2 enabled and the load balancer's default desync mitigation mode.
 type ContainerComputeLoadBalancerProps struct {
-	Name                   string
-	ListenerCertificateArn string
-	vpc                    ec2.IVpc
+	Name                             string
+	ListenerCertificateArn           string
+	Scheme                           LoadBalancerScheme
+	SubnetType                       ec2.SubnetType
+	SubnetIds                        []string
+	IpAddressType                    elbv2.IpAddressType
+	IdleTimeoutSeconds               float64
+	IsDeletionProtectionEnabled      bool
+	IsHttp2Disabled                  bool
+	DesyncMitigationMode             elbv2.DesyncMitigationMode
+	IsDropInvalidHeaderFieldsEnabled bool
+	IngressPeers                     []ec2.IPeer
 }
 
 type ContainerComputeCloudmapNamespaceProps struct {
 	Name        string
 	Description string
-	vpc         ec2.IVpc
 }
 
 type securityGroupProps struct {
 	Name        string
 	Description string
-	vpc         ec2.IVpc
 }
 
 type AutoscalinGroupCapacityProviders struct {
@@ -78,40 +183,87 @@ type AutoscalinGroupCapacityProviders struct {
 }
 
 type ContainerComputeProps struct {
-	VpcId                *string
-	Cluster              ContainerComputeClusterProps
-	AsgCapacityProviders []AutoscalinGroupCapacityProviders
-	LoadBalancer         ContainerComputeLoadBalancerProps
-	CloudmapNamespace    ContainerComputeCloudmapNamespaceProps
+	VpcId                        *string
+	Vpc                          ec2.IVpc
+	Cluster                      ContainerComputeClusterProps
+	AsgCapacityProviders         []AutoscalinGroupCapacityProviders
+	LoadBalancer                 ContainerComputeLoadBalancerProps
+	IsNetworkLoadBalancerEnabled bool
+	NetworkLoadBalancer          ContainerComputeNetworkLoadBalancerProps
+	CloudmapNamespace            ContainerComputeCloudmapNamespaceProps
+	IsVpcEndpointsEnabled        bool
+	VpcEndpoints                 ContainerComputeVpcEndpointsProps
+	IsParametersEnabled          bool
+	Parameters                   ContainerComputeParametersProps
 }
 
 func NewContainerCompute(scope constructs.Construct, id *string, props *ContainerComputeProps) ContainerCompute {
 
 	this := constructs.NewConstruct(scope, id)
 
-	vpc = LookupVpc(scope, jsii.String("LookUpVpc"), &network.VpcProps{Id: *props.VpcId})
+	vpcProps := network.VpcProps{Vpc: props.Vpc}
+	if props.VpcId != nil {
+		vpcProps.Id = *props.VpcId
+	}
+	vpc := LookupVpc(this, jsii.String("LookUpVpc"), &vpcProps)
 
-	cluster := createCluster(this, jsii.String("EcsCluster"), &props.Cluster)
+	cluster := createCluster(this, jsii.String("EcsCluster"), &props.Cluster, vpc)
 
+	var vpcEndpoints constructs.Construct = nil
+	if props.IsVpcEndpointsEnabled {
+		asgProps := []*ContainerComputeAsgProps{}
+		if props.Cluster.IsAsgCapacityProviderEnabled {
+			for index := range props.AsgCapacityProviders {
+				asgProps = append(asgProps, &props.AsgCapacityProviders[index].AutoScalingGroup)
+			}
+		}
+		vpcEndpoints = createVpcEndpoints(this, jsii.String("VpcEndpoints"), &props.VpcEndpoints, asgProps, vpc)
+	}
+
+	asgSecurityGroups := []ec2.ISecurityGroup{}
+	capacityProviderNames := []*string{}
 	if props.Cluster.IsAsgCapacityProviderEnabled {
 		for _, asgCapacityProvider := range props.AsgCapacityProviders {
 
-			autoScalingGroup := createAutoScalingGroup(this, jsii.String(asgCapacityProvider.AutoScalingGroup.Name+"AutoscalingGroup"), &asgCapacityProvider.AutoScalingGroup, *cluster.ClusterName())
+			autoScalingGroup := createAutoScalingGroup(this, jsii.String(asgCapacityProvider.AutoScalingGroup.Name+"AutoscalingGroup"), &asgCapacityProvider.AutoScalingGroup, *cluster.ClusterName(), vpc)
+			if vpcEndpoints != nil {
+				// instances in isolated subnets can only register with the cluster once the endpoints exist
+				autoScalingGroup.Node().AddDependency(vpcEndpoints)
+			}
 
-			capacityProvider := createCapacityProvider(this, jsii.String(asgCapacityProvider.CapacityProvider.Name+"AsgCapacityProvider"), &asgCapacityProvider.CapacityProvider, autoScalingGroup)
+			capacityProvider := createCapacityProvider(this, jsii.String(asgCapacityProvider.CapacityProvider.Name+"AsgCapacityProvider"), &asgCapacityProvider.CapacityProvider, autoScalingGroup, asgCapacityProvider.AutoScalingGroup.MachineImage)
 
 			cluster.AddAsgCapacityProvider(capacityProvider, &ecs.AddAutoScalingGroupCapacityOptions{})
+			// bridge mode services reach and are reached through the cluster's connections
+			cluster.Connections().AddSecurityGroup(*autoScalingGroup.Connections().SecurityGroups()...)
+			asgSecurityGroups = append(asgSecurityGroups, *autoScalingGroup.Connections().SecurityGroups()...)
+			capacityProviderNames = append(capacityProviderNames, capacityProvider.CapacityProviderName())
+			if isBottlerocket(asgCapacityProvider.AutoScalingGroup.MachineImage) {
+				configureBottlerocketSettings(autoScalingGroup, &asgCapacityProvider.AutoScalingGroup)
+			}
 		}
 	}
-	loadBalancer := createLoadBalancer(this, jsii.String("LoadBalanerSetup"), &props.LoadBalancer)
+	loadBalancer := createLoadBalancer(this, jsii.String("LoadBalanerSetup"), &props.LoadBalancer, vpc)
 
-	httpsListener := createHttpsListener(this, jsii.String("HttpsListener"), &props.LoadBalancer, loadBalancer)
+	httpsListener := createHttpsListener(this, jsii.String("HttpsListener"), &props.LoadBalancer, loadBalancer, vpc)
 
 	createHttpListener(this, jsii.String("HttpListener"), loadBalancer)
 
-	cloudmapNamespace := createCloudMapNamespace(this, jsii.String("CloudMapNamespace"), &props.CloudmapNamespace)
+	cloudmapNamespace := createCloudMapNamespace(this, jsii.String("CloudMapNamespace"), &props.CloudmapNamespace, vpc)
+
+	var networkLoadBalancer elbv2.INetworkLoadBalancer = nil
+	networkTargetGroups := map[string]elbv2.INetworkTargetGroup{}
+	if props.IsNetworkLoadBalancerEnabled {
+		nlb := createNetworkLoadBalancer(this, jsii.String("NetworkLoadBalancer"), &props.NetworkLoadBalancer, vpc)
+		networkTargetGroups = addNetworkListeners(this, nlb, &props.NetworkLoadBalancer, vpc)
+		networkLoadBalancer = nlb
+	}
 
-	return &containerCompute{this, cluster, loadBalancer, cloudmapNamespace, httpsListener}
+	compute := &containerCompute{this, cluster, loadBalancer, cloudmapNamespace, httpsListener, vpc, networkLoadBalancer, networkTargetGroups}
+	if props.IsParametersEnabled {
+		createContainerComputeParameters(this, jsii.String("Parameters"), &props.Parameters, compute, asgSecurityGroups, capacityProviderNames)
+	}
+	return compute
 }
 
 func (c *containerCompute) Cluster() ecs.ICluster {
@@ -130,14 +282,47 @@ func (hl *containerCompute) HttpsListener() elbv2.IApplicationListener {
 	return hl.httpsListener
 }
 
+func (v *containerCompute) Vpc() ec2.IVpc {
+	return v.vpc
+}
+
+func (c *containerCompute) NetworkLoadBalancer() elbv2.INetworkLoadBalancer {
+	return c.networkLoadBalancer
+}
+
+func (c *containerCompute) NetworkTargetGroups() map[string]elbv2.INetworkTargetGroup {
+	return c.networkTargetGroups
+}
+
+// LookupVpc returns props.Vpc when it is set and otherwise looks the VPC up by its ID (or as the default VPC).
 func LookupVpc(scope constructs.Construct, id *string, props *network.VpcProps) ec2.IVpc {
+	validateVpcProps(props)
+	if props.Vpc != nil {
+		return props.Vpc
+	}
+
+	var isDefault *bool = nil
+	if props.IsDefault {
+		isDefault = jsii.Bool(true)
+	}
 	vpc := ec2.Vpc_FromLookup(scope, id, &ec2.VpcLookupOptions{
-		VpcId: jsii.String(props.Id),
+		VpcId:     optionalString(props.Id),
+		IsDefault: isDefault,
 	})
 	return vpc
 }
 
-func createCluster(scope constructs.Construct, id *string, props *ContainerComputeClusterProps) ecs.Cluster {
+func validateVpcProps(props *network.VpcProps) {
+	isLookup := props.Id != "" || props.IsDefault
+	if isLookup && props.Vpc != nil {
+		panic("vpc must be given either as an ID to look up or as an existing ec2.IVpc, not both")
+	}
+	if !isLookup && props.Vpc == nil {
+		panic("vpc must be given either as an ID to look up or as an existing ec2.IVpc")
+	}
+}
+
+func createCluster(scope constructs.Construct, id *string, props *ContainerComputeClusterProps, vpc ec2.IVpc) ecs.Cluster {
 	if props.IsFargateCapacityProviderEnabled {
 		cluster := ecs.NewCluster(scope, id, &ecs.ClusterProps{
 			ClusterName:                    jsii.String(props.Name),
@@ -157,7 +342,7 @@ func createCluster(scope constructs.Construct, id *string, props *ContainerCompu
 	}
 }
 
-func createLbSecurityGroup(scope constructs.Construct, id *string, props *securityGroupProps, vpc ec2.IVpc) ec2.ISecurityGroup {
+func createLbSecurityGroup(scope constructs.Construct, id *string, props *securityGroupProps, peers []ec2.IPeer, vpc ec2.IVpc) ec2.ISecurityGroup {
 	lbSecurityGroup := ec2.NewSecurityGroup(scope, id, &ec2.SecurityGroupProps{
 		AllowAllOutbound:  jsii.Bool(true),
 		Vpc:               vpc,
@@ -165,48 +350,107 @@ func createLbSecurityGroup(scope constructs.Construct, id *string, props *securi
 		Description:       &props.Description,
 	})
 
-	lbSecurityGroup.AddIngressRule(
-		ec2.Peer_AnyIpv4(),
-		ec2.Port_Tcp(jsii.Number(443)),
-		jsii.String("Default HTTPS Port"),
-		jsii.Bool(false),
-	)
-
-	lbSecurityGroup.AddIngressRule(
-		ec2.Peer_AnyIpv4(),
-		ec2.Port_Tcp(jsii.Number(80)),
-		jsii.String("Default HTTP Port"),
-		jsii.Bool(false),
-	)
+	for _, peer := range peers {
+		lbSecurityGroup.AddIngressRule(
+			peer,
+			ec2.Port_Tcp(jsii.Number(443)),
+			jsii.String("Default HTTPS Port"),
+			jsii.Bool(false),
+		)
+
+		lbSecurityGroup.AddIngressRule(
+			peer,
+			ec2.Port_Tcp(jsii.Number(80)),
+			jsii.String("Default HTTP Port"),
+			jsii.Bool(false),
+		)
+	}
 
 	return lbSecurityGroup
 }
 
-func createLoadBalancer(scope constructs.Construct, id *string, props *ContainerComputeLoadBalancerProps) elbv2.IApplicationLoadBalancer {
+func createLoadBalancer(scope constructs.Construct, id *string, props *ContainerComputeLoadBalancerProps, vpc ec2.IVpc) elbv2.IApplicationLoadBalancer {
+	isInternetFacing := isLoadBalancerInternetFacing(props.Scheme)
+	ipAddressType := props.IpAddressType
+	if ipAddressType == "" {
+		ipAddressType = DEFAULT_LOAD_BALANCER_IP_ADDRESS_TYPE
+	}
+	idleTimeout := props.IdleTimeoutSeconds
+	if idleTimeout == 0 {
+		idleTimeout = DEFAULT_LOAD_BALANCER_IDLE_TIMEOUT_SECONDS
+	}
+
+	peers := props.IngressPeers
+	if len(peers) == 0 {
+		peers = []ec2.IPeer{ec2.Peer_Ipv4(vpc.VpcCidrBlock())}
+		if isInternetFacing {
+			peers = []ec2.IPeer{ec2.Peer_AnyIpv4()}
+			if ipAddressType == elbv2.IpAddressType_DUAL_STACK {
+				peers = append(peers, ec2.Peer_AnyIpv6())
+			}
+		}
+	}
+
 	lb := elbv2.NewApplicationLoadBalancer(scope, id, &elbv2.ApplicationLoadBalancerProps{
-		LoadBalancerName: jsii.String(props.Name),
-		Vpc:              vpc,
-		InternetFacing:   jsii.Bool(true),
-		VpcSubnets:       &ec2.SubnetSelection{SubnetType: ec2.SubnetType_PUBLIC},
-		IdleTimeout:      awscdk.Duration_Seconds(jsii.Number(120)),
-		IpAddressType:    elbv2.IpAddressType_IPV4,
+		LoadBalancerName:        jsii.String(props.Name),
+		Vpc:                     vpc,
+		InternetFacing:          jsii.Bool(isInternetFacing),
+		VpcSubnets:              createLoadBalancerSubnetSelection(scope, props.Name, isInternetFacing, props.SubnetType, props.SubnetIds),
+		IdleTimeout:             awscdk.Duration_Seconds(jsii.Number(idleTimeout)),
+		IpAddressType:           ipAddressType,
+		DeletionProtection:      jsii.Bool(props.IsDeletionProtectionEnabled),
+		Http2Enabled:            jsii.Bool(!props.IsHttp2Disabled),
+		DesyncMitigationMode:    props.DesyncMitigationMode,
+		DropInvalidHeaderFields: jsii.Bool(props.IsDropInvalidHeaderFieldsEnabled),
 		SecurityGroup: createLbSecurityGroup(scope, jsii.String(props.Name+"SecurityGroup"), &securityGroupProps{
 			Name:        props.Name + "SecurityGroup",
 			Description: "Security group for " + props.Name,
 		},
+			peers,
 			vpc,
 		),
 	})
 	return lb
 }
 
-func createHttpsListener(scope constructs.Construct, id *string, props *ContainerComputeLoadBalancerProps, lb elbv2.IApplicationLoadBalancer) elbv2.IApplicationListener {
+func isLoadBalancerInternetFacing(scheme LoadBalancerScheme) bool {
+	switch scheme {
+	case "", LOAD_BALANCER_SCHEME_INTERNET_FACING:
+		return true
+	case LOAD_BALANCER_SCHEME_INTERNAL:
+		return false
+	default:
+		panic(fmt.Sprintf("invalid load balancer scheme %q", scheme))
+	}
+}
+
+func createLoadBalancerSubnetSelection(scope constructs.Construct, name string, isInternetFacing bool, subnetType ec2.SubnetType, subnetIds []string) *ec2.SubnetSelection {
+	if len(subnetIds) > 0 {
+		subnets := []ec2.ISubnet{}
+		for _, subnetId := range subnetIds {
+			subnets = append(subnets, ec2.Subnet_FromSubnetId(scope, jsii.String(name+"Subnet"+subnetId), jsii.String(subnetId)))
+		}
+		return &ec2.SubnetSelection{Subnets: &subnets}
+	}
+
+	if subnetType == "" {
+		subnetType = ec2.SubnetType_PUBLIC
+		if !isInternetFacing {
+			subnetType = DEFAULT_INTERNAL_LOAD_BALANCER_SUBNET_TYPE
+		}
+	}
+	return &ec2.SubnetSelection{SubnetType: subnetType}
+}
+
+func createHttpsListener(scope constructs.Construct, id *string, props *ContainerComputeLoadBalancerProps, lb elbv2.IApplicationLoadBalancer, vpc ec2.IVpc) elbv2.IApplicationListener {
 	httpsListener := elbv2.NewApplicationListener(scope, jsii.String("LoadbalancerHttpsListener"), &elbv2.ApplicationListenerProps{
 		LoadBalancer: lb,
 		Certificates: &[]elbv2.IListenerCertificate{
 			elbv2.ListenerCertificate_FromArn(jsii.String(props.ListenerCertificateArn))},
 		Protocol: elbv2.ApplicationProtocol_HTTPS,
 		Port:     jsii.Number(443),
+		// ingress is managed on the load balancer security group, which only admits the VPC when internal
+		Open: jsii.Bool(false),
 		DefaultTargetGroups: &[]elbv2.IApplicationTargetGroup{
 			elbv2.NewApplicationTargetGroup(
 				scope,
@@ -229,6 +473,7 @@ func createHttpListener(scope constructs.Construct, id *string, lb elbv2.IApplic
 	elbv2.NewApplicationListener(scope, jsii.String("LoadbalancerHttpListener"), &elbv2.ApplicationListenerProps{
 		Port:         jsii.Number(80),
 		LoadBalancer: lb,
+		Open:         jsii.Bool(false),
 		DefaultAction: elbv2.ListenerAction_Redirect(
 			&elbv2.RedirectOptions{
 				Host:      jsii.String("#{host}"),
@@ -241,7 +486,7 @@ func createHttpListener(scope constructs.Construct, id *string, lb elbv2.IApplic
 	})
 }
 
-func createCloudMapNamespace(scope constructs.Construct, id *string, props *ContainerComputeCloudmapNamespaceProps) servicediscovery.IPrivateDnsNamespace {
+func createCloudMapNamespace(scope constructs.Construct, id *string, props *ContainerComputeCloudmapNamespaceProps, vpc ec2.IVpc) servicediscovery.IPrivateDnsNamespace {
 	cloudmapNamespace := servicediscovery.NewPrivateDnsNamespace(scope, id, &servicediscovery.PrivateDnsNamespaceProps{
 		Name:        jsii.String(props.Name),
 		Description: jsii.String(props.Description),
@@ -250,7 +495,7 @@ func createCloudMapNamespace(scope constructs.Construct, id *string, props *Cont
 	return cloudmapNamespace
 }
 
-func createAsgSecurityGroup(scope constructs.Construct, id *string, props *securityGroupProps) ec2.ISecurityGroup {
+func createAsgSecurityGroup(scope constructs.Construct, id *string, props *securityGroupProps, vpc ec2.IVpc) ec2.ISecurityGroup {
 	asgSecurityGroup := ec2.NewSecurityGroup(scope, id, &ec2.SecurityGroupProps{
 		AllowAllOutbound:  jsii.Bool(true),
 		Vpc:               vpc,
@@ -260,7 +505,8 @@ func createAsgSecurityGroup(scope constructs.Construct, id *string, props *secur
 	return asgSecurityGroup
 }
 
-func createAsgPolicyDocument() iam.PolicyDocument {
+// createRexrayPolicyDocument lets the rexray/ebs plugin create and attach the volumes of tasks with legacy volumes
+func createRexrayPolicyDocument() iam.PolicyDocument {
 	pd := iam.NewPolicyDocument(&iam.PolicyDocumentProps{
 		Statements: &[]iam.PolicyStatement{iam.NewPolicyStatement(&iam.PolicyStatementProps{Effect: iam.Effect_ALLOW,
 			Actions: &[]*string{
@@ -282,73 +528,298 @@ func createAsgPolicyDocument() iam.PolicyDocument {
 	return pd
 }
 
-func createAsgRole(scope constructs.Construct, id *string, props *ContainerComputeAsgProps, policyDocument iam.PolicyDocument) iam.IRole {
+func createAsgRole(scope constructs.Construct, id *string, props *ContainerComputeAsgProps) iam.IRole {
+	inlinePolicies := map[string]iam.PolicyDocument{}
+	if props.IsRexrayPluginEnabled {
+		if isBottlerocket(props.MachineImage) {
+			panic("autoscaling group " + props.Name + " cannot install the rexray/ebs plugin on Bottlerocket")
+		}
+		inlinePolicies["Ec2VolumeAccess"] = createRexrayPolicyDocument()
+	}
 	role := iam.NewRole(scope, id, &iam.RoleProps{
 		Description:    jsii.String("Iam role for autoscaling group " + props.Name),
-		InlinePolicies: &map[string]iam.PolicyDocument{"Ec2VolumeAccess": policyDocument},
+		InlinePolicies: &inlinePolicies,
 		RoleName:       jsii.String(props.Name + "InstanceProfileRole"),
 		AssumedBy:      iam.NewServicePrincipal(jsii.String("ec2.amazonaws.com"), &iam.ServicePrincipalOpts{}),
 	})
+	// Bottlerocket capacity providers get the policy from the cluster already
+	if props.IsSsmAccessEnabled && !isBottlerocket(props.MachineImage) {
+		role.AddManagedPolicy(iam.ManagedPolicy_FromAwsManagedPolicyName(jsii.String("AmazonSSMManagedInstanceCore")))
+	}
 	return role
 }
 
-func createAutoScalingGroup(scope constructs.Construct, id *string, props *ContainerComputeAsgProps, clusterName string) autoscaling.IAutoScalingGroup {
-	asgPolicyDocument := createAsgPolicyDocument()
+func createAutoScalingGroup(scope constructs.Construct, id *string, props *ContainerComputeAsgProps, clusterName string, vpc ec2.IVpc) autoscaling.AutoScalingGroup {
+	role := createAsgRole(scope, jsii.String("IamRole"+props.Name), props)
 
-	role := createAsgRole(scope, jsii.String("IamRole"+props.Name), props, asgPolicyDocument)
+	securityGroup := createAsgSecurityGroup(scope, jsii.String(props.Name+"SecurityGroup"), &securityGroupProps{
+		Name:        props.Name + "SecurityGroup",
+		Description: "SecurityGroup for " + props.Name,
+	}, vpc)
+	userData := createAsgUserData(props.MachineImage)
+
+	// a mixed instances policy takes its instance types from the overrides instead of the launch template
+	var instanceType ec2.InstanceType = nil
+	if !props.IsMixedInstancesPolicyEnabled {
+		instanceType = ec2.InstanceType_Of(props.InstanceClass, props.InstanceSize)
+	}
+	launchTemplate := createAsgLaunchTemplate(scope, jsii.String(props.Name+"LaunchTemplate"), props, instanceType, securityGroup, userData, role)
 
-	asg := autoscaling.NewAutoScalingGroup(scope, id, &autoscaling.AutoScalingGroupProps{
+	asgProps := &autoscaling.AutoScalingGroupProps{
 		AutoScalingGroupName: jsii.String(props.Name),
 		MinCapacity:          jsii.Number(props.MinCapacity),
 		MaxCapacity:          jsii.Number(props.MaxCapacity),
-		InstanceType:         ec2.InstanceType_Of(props.InstanceClass, props.InstanceSize),
-		MachineImage:         createMachineImage(),
-		SecurityGroup: createAsgSecurityGroup(scope, jsii.String(props.Name+"SecurityGroup"), &securityGroupProps{
-			Name:        props.Name + "SecurityGroup",
-			Description: "SecurityGroup for " + props.Name,
-			vpc:         vpc,
-		}),
-
-		UserData:   ec2.UserData_ForLinux(&ec2.LinuxUserDataOptions{Shebang: jsii.String("#!/bin/bash")}),
-		VpcSubnets: &ec2.SubnetSelection{SubnetType: ec2.SubnetType_PUBLIC},
-		Vpc:        vpc,
-		KeyName:    jsii.String(props.SshKeyName),
-		Role:       role,
+		VpcSubnets:           createAsgSubnetSelection(scope, props),
+		Vpc:                  vpc,
+	}
+	if props.IsMixedInstancesPolicyEnabled {
+		asgProps.MixedInstancesPolicy = createMixedInstancesPolicy(props, launchTemplate)
+	} else {
+		asgProps.LaunchTemplate = launchTemplate
+	}
+	asg := autoscaling.NewAutoScalingGroup(scope, id, asgProps)
+
+	asg.UserData().AddCommands(createAsgBootstrapCommands(props, clusterName)...)
+	return asg
+}
+
+func createAsgSubnetSelection(scope constructs.Construct, props *ContainerComputeAsgProps) *ec2.SubnetSelection {
+	if len(props.SubnetIds) > 0 {
+		subnets := []ec2.ISubnet{}
+		for _, subnetId := range props.SubnetIds {
+			subnets = append(subnets, ec2.Subnet_FromSubnetId(scope, jsii.String(props.Name+"Subnet"+subnetId), jsii.String(subnetId)))
+		}
+		return &ec2.SubnetSelection{Subnets: &subnets}
+	}
+
+	subnetType := props.SubnetType
+	if subnetType == "" {
+		subnetType = DEFAULT_ASG_SUBNET_TYPE
+	}
+	return &ec2.SubnetSelection{SubnetType: subnetType}
+}
+
+// createAsgLaunchTemplate requires IMDSv2 and encrypts the volume the instance keeps its containers on, the root
+// volume on Amazon Linux and the data volume on Bottlerocket.
+func createAsgLaunchTemplate(scope constructs.Construct, id *string, props *ContainerComputeAsgProps, instanceType ec2.InstanceType, securityGroup ec2.ISecurityGroup, userData ec2.UserData, role iam.IRole) ec2.LaunchTemplate {
+	hopLimit := props.InstanceMetadata.HttpPutResponseHopLimit
+	if hopLimit == 0 {
+		hopLimit = DEFAULT_INSTANCE_METADATA_HOP_LIMIT
+	}
+
+	launchTemplate := ec2.NewLaunchTemplate(scope, id, &ec2.LaunchTemplateProps{
+		LaunchTemplateName:      id,
+		InstanceType:            instanceType,
+		MachineImage:            createMachineImage(props.MachineImage),
+		SecurityGroup:           securityGroup,
+		UserData:                userData,
+		KeyName:                 optionalString(props.SshKeyName),
+		Role:                    role,
+		BlockDevices:            createAsgBlockDevices(scope, jsii.String(*id+"VolumeKey"), props),
+		RequireImdsv2:           jsii.Bool(true),
+		HttpPutResponseHopLimit: jsii.Number(hopLimit),
+		InstanceMetadataTags:    jsii.Bool(props.InstanceMetadata.IsInstanceTagsEnabled),
+		DetailedMonitoring:      jsii.Bool(props.IsDetailedMonitoringEnabled),
 	})
+	return launchTemplate
+}
 
-	asg.UserData().AddCommands(
-		jsii.String("sudo yum -y update"),
-		jsii.String("sudo yum -y install wget"),
-		jsii.String("sudo touch /etc/ecs/ecs.config"),
-		jsii.String("sudo amazon-linux-extras disable docker"),
-		jsii.String("sudo amazon-linux-extras install -y ecs"),
+func createAsgBlockDevices(scope constructs.Construct, id *string, props *ContainerComputeAsgProps) *[]*ec2.BlockDevice {
+	rootVolume := &props.RootVolume
+
+	deviceName := DEFAULT_ROOT_VOLUME_DEVICE_NAME
+	if isBottlerocket(props.MachineImage) {
+		deviceName = BOTTLEROCKET_DATA_VOLUME_DEVICE_NAME
+	}
+	size := rootVolume.SizeGiB
+	if size == 0 {
+		size = DEFAULT_ROOT_VOLUME_SIZE_GIB
+	}
+	volumeType := rootVolume.VolumeType
+	if volumeType == "" {
+		volumeType = DEFAULT_ROOT_VOLUME_TYPE
+	}
+	var kmsKey kms.IKey = nil
+	if rootVolume.KmsKeyArn != "" {
+		kmsKey = kms.Key_FromKeyArn(scope, id, jsii.String(rootVolume.KmsKeyArn))
+	}
+
+	blockDevices := []*ec2.BlockDevice{
+		{
+			DeviceName: jsii.String(deviceName),
+			Volume: ec2.BlockDeviceVolume_Ebs(jsii.Number(size), &ec2.EbsDeviceOptions{
+				VolumeType:          volumeType,
+				Iops:                optionalNumber(rootVolume.Iops),
+				Encrypted:           jsii.Bool(true),
+				KmsKey:              kmsKey,
+				DeleteOnTermination: jsii.Bool(true),
+			}),
+		},
+	}
+	// the Bottlerocket OS volume keeps the size of the AMI's snapshot but has to be encrypted as well
+	if isBottlerocket(props.MachineImage) {
+		blockDevices = append(blockDevices, &ec2.BlockDevice{
+			DeviceName: jsii.String(DEFAULT_ROOT_VOLUME_DEVICE_NAME),
+			Volume: ec2.NewBlockDeviceVolume(&ec2.EbsDeviceProps{
+				VolumeType:          DEFAULT_ROOT_VOLUME_TYPE,
+				Encrypted:           jsii.Bool(true),
+				KmsKey:              kmsKey,
+				DeleteOnTermination: jsii.Bool(true),
+			}, nil),
+		})
+	}
+	return &blockDevices
+}
+
+func createMixedInstancesPolicy(props *ContainerComputeAsgProps, launchTemplate ec2.ILaunchTemplate) *autoscaling.MixedInstancesPolicy {
+	policyProps := &props.MixedInstancesPolicy
+	if len(policyProps.InstanceTypes) == 0 {
+		panic("mixed instances policy of autoscaling group " + props.Name + " requires at least one instance type")
+	}
+	if policyProps.OnDemandPercentageAboveBaseCapacity < 0 || policyProps.OnDemandPercentageAboveBaseCapacity > 100 {
+		panic(fmt.Sprintf("invalid on-demand percentage %v for autoscaling group %s: must be between 0 and 100", policyProps.OnDemandPercentageAboveBaseCapacity, props.Name))
+	}
+
+	overrides := []*autoscaling.LaunchTemplateOverrides{}
+	for _, instanceType := range policyProps.InstanceTypes {
+		overrides = append(overrides, &autoscaling.LaunchTemplateOverrides{
+			InstanceType: ec2.NewInstanceType(jsii.String(instanceType)),
+		})
+	}
+
+	spotAllocationStrategy := policyProps.SpotAllocationStrategy
+	if spotAllocationStrategy == "" {
+		spotAllocationStrategy = DEFAULT_SPOT_ALLOCATION_STRATEGY
+	}
+
+	return &autoscaling.MixedInstancesPolicy{
+		LaunchTemplate:          launchTemplate,
+		LaunchTemplateOverrides: &overrides,
+		InstancesDistribution: &autoscaling.InstancesDistribution{
+			OnDemandBaseCapacity:                jsii.Number(policyProps.OnDemandBaseCapacity),
+			OnDemandPercentageAboveBaseCapacity: jsii.Number(policyProps.OnDemandPercentageAboveBaseCapacity),
+			SpotAllocationStrategy:              spotAllocationStrategy,
+			SpotMaxPrice:                        optionalString(policyProps.SpotMaxPrice),
+		},
+	}
+}
+
+func createMachineImage(imageType MachineImageType) ec2.IMachineImage {
+	switch imageType {
+	case "", MACHINE_IMAGE_TYPE_AMAZON_LINUX_2:
+		image := ec2.NewAmazonLinuxImage(&ec2.AmazonLinuxImageProps{
+			CpuType:        ec2.AmazonLinuxCpuType_X86_64,
+			Edition:        ec2.AmazonLinuxEdition_STANDARD,
+			Generation:     ec2.AmazonLinuxGeneration_AMAZON_LINUX_2,
+			Virtualization: ec2.AmazonLinuxVirt_HVM,
+			Kernel:         ec2.AmazonLinuxKernel_KERNEL5_X,
+		})
+		return image
+	case MACHINE_IMAGE_TYPE_ECS_AMAZON_LINUX_2:
+		return ecs.EcsOptimizedImage_AmazonLinux2(ecs.AmiHardwareType_STANDARD, &ecs.EcsOptimizedImageOptions{})
+	case MACHINE_IMAGE_TYPE_ECS_AMAZON_LINUX_2_ARM64:
+		return ecs.EcsOptimizedImage_AmazonLinux2(ecs.AmiHardwareType_ARM, &ecs.EcsOptimizedImageOptions{})
+	case MACHINE_IMAGE_TYPE_ECS_AMAZON_LINUX_2023:
+		return ec2.MachineImage_FromSsmParameter(jsii.String(ECS_AMAZON_LINUX_2023_AMI_PARAMETER), &ec2.SsmParameterImageOptions{Os: ec2.OperatingSystemType_LINUX})
+	case MACHINE_IMAGE_TYPE_ECS_AMAZON_LINUX_2023_ARM64:
+		return ec2.MachineImage_FromSsmParameter(jsii.String(ECS_AMAZON_LINUX_2023_ARM64_AMI_PARAMETER), &ec2.SsmParameterImageOptions{Os: ec2.OperatingSystemType_LINUX})
+	case MACHINE_IMAGE_TYPE_BOTTLEROCKET:
+		return ecs.NewBottleRocketImage(&ecs.BottleRocketImageProps{Architecture: ec2.InstanceArchitecture_X86_64})
+	case MACHINE_IMAGE_TYPE_BOTTLEROCKET_ARM64:
+		return ecs.NewBottleRocketImage(&ecs.BottleRocketImageProps{Architecture: ec2.InstanceArchitecture_ARM_64})
+	default:
+		panic(fmt.Sprintf("invalid machine image type %q", imageType))
+	}
+}
+
+func isBottlerocket(imageType MachineImageType) bool {
+	return imageType == MACHINE_IMAGE_TYPE_BOTTLEROCKET || imageType == MACHINE_IMAGE_TYPE_BOTTLEROCKET_ARM64
+}
+
+func convertEcsMachineImageType(imageType MachineImageType) ecs.MachineImageType {
+	if isBottlerocket(imageType) {
+		return ecs.MachineImageType_BOTTLEROCKET
+	}
+	return ecs.MachineImageType_AMAZON_LINUX_2
+}
+
+// createAsgUserData returns a shell script for the Amazon Linux images and an empty TOML document for Bottlerocket
+func createAsgUserData(imageType MachineImageType) ec2.UserData {
+	if isBottlerocket(imageType) {
+		return ec2.UserData_Custom(jsii.String(""))
+	}
+	return ec2.UserData_ForLinux(&ec2.LinuxUserDataOptions{Shebang: jsii.String("#!/bin/bash")})
+}
+
+// createAsgBootstrapCommands returns the user data registering the instance with the cluster. Plain Amazon Linux 2
+// installs the ECS agent itself, the ECS-optimized images ship with it. Bottlerocket is configured through TOML
+// settings once the group joins the cluster, see configureBottlerocketSettings.
+func createAsgBootstrapCommands(props *ContainerComputeAsgProps, clusterName string) []*string {
+	if isBottlerocket(props.MachineImage) {
+		return []*string{}
+	}
+
+	commands := []*string{}
+	if props.MachineImage == "" || props.MachineImage == MACHINE_IMAGE_TYPE_AMAZON_LINUX_2 {
+		commands = append(commands,
+			jsii.String("sudo yum -y update"),
+			jsii.String("sudo yum -y install wget"),
+			jsii.String("sudo touch /etc/ecs/ecs.config"),
+			jsii.String("sudo amazon-linux-extras disable docker"),
+			jsii.String("sudo amazon-linux-extras install -y ecs"),
+		)
+	}
+	commands = append(commands,
 		jsii.String("echo \"ECS_CLUSTER="+clusterName+"\" >>  /etc/ecs/ecs.config"),
 		jsii.String("echo \"ECS_AWSVPC_BLOCK_IMDS=true\" >> /etc/ecs/ecs.config"),
-		jsii.String("sudo systemctl enable --now --no-block ecs.service"),
-		jsii.String("docker plugin install rexray/ebs REXRAY_PREEMPT=true EBS_REGION="+*awscdk.Aws_REGION()+" --grant-all-permissions"),
 	)
-	return asg
+	if props.IsMixedInstancesPolicyEnabled {
+		// lets the ECS agent drain tasks from a Spot instance once it receives the interruption notice
+		commands = append(commands, jsii.String("echo \"ECS_ENABLE_SPOT_INSTANCE_DRAINING=true\" >> /etc/ecs/ecs.config"))
+	}
+	if props.MachineImage == "" || props.MachineImage == MACHINE_IMAGE_TYPE_AMAZON_LINUX_2 {
+		commands = append(commands, jsii.String("sudo systemctl enable --now --no-block ecs.service"))
+	}
+	if props.IsRexrayPluginEnabled {
+		commands = append(commands,
+			jsii.String("docker plugin install rexray/ebs REXRAY_PREEMPT=true EBS_REGION="+*awscdk.Aws_REGION()+" --grant-all-permissions"),
+		)
+	}
+	return commands
 }
 
-func createMachineImage() ec2.IMachineImage {
-	image := ec2.NewAmazonLinuxImage(&ec2.AmazonLinuxImageProps{
-		CpuType:        ec2.AmazonLinuxCpuType_X86_64,
-		Edition:        ec2.AmazonLinuxEdition_STANDARD,
-		Generation:     ec2.AmazonLinuxGeneration_AMAZON_LINUX_2,
-		Virtualization: ec2.AmazonLinuxVirt_HVM,
-		Kernel:         ec2.AmazonLinuxKernel_KERNEL5_X,
-	})
-	return image
+// configureBottlerocketSettings extends the [settings.ecs] table the cluster writes when the group is added as a
+// capacity provider, TOML does not allow the table to be declared twice.
+func configureBottlerocketSettings(asg autoscaling.AutoScalingGroup, props *ContainerComputeAsgProps) {
+	asg.UserData().AddCommands(
+		jsii.String("awsvpc-block-imds = true"),
+	)
+	if props.IsMixedInstancesPolicyEnabled {
+		asg.UserData().AddCommands(
+			jsii.String("enable-spot-instance-draining = true"),
+		)
+	}
 }
 
-func createCapacityProvider(scope constructs.Construct, id *string, props *ContainerComputeAsgCapacityProviderProps, asg autoscaling.IAutoScalingGroup) ecs.AsgCapacityProvider {
+func createCapacityProvider(scope constructs.Construct, id *string, props *ContainerComputeAsgCapacityProviderProps, asg autoscaling.IAutoScalingGroup, imageType MachineImageType) ecs.AsgCapacityProvider {
+	targetCapacityPercent := props.TargetCapacityPercent
+	if targetCapacityPercent == 0 {
+		targetCapacityPercent = DEFAULT_TARGET_CAPACITY_PERCENT
+	}
+	if targetCapacityPercent < 1 || targetCapacityPercent > 100 {
+		panic(fmt.Sprintf("invalid target capacity percent %v for capacity provider %s: must be between 1 and 100", targetCapacityPercent, props.Name))
+	}
+
 	asgCapacityProvider := ecs.NewAsgCapacityProvider(scope, id, &ecs.AsgCapacityProviderProps{
 		AutoScalingGroup:                   asg,
 		EnableManagedScaling:               jsii.Bool(true),
-		EnableManagedTerminationProtection: jsii.Bool(false),
-		TargetCapacityPercent:              jsii.Number(100),
+		EnableManagedTerminationProtection: jsii.Bool(props.IsManagedTerminationProtectionEnabled),
+		TargetCapacityPercent:              jsii.Number(targetCapacityPercent),
+		MinimumScalingStepSize:             optionalNumber(props.MinimumScalingStepSize),
+		MaximumScalingStepSize:             optionalNumber(props.MaximumScalingStepSize),
 		CapacityProviderName:               jsii.String(props.Name),
 		CanContainersAccessInstanceRole:    jsii.Bool(true),
+		MachineImageType:                   convertEcsMachineImageType(imageType),
 	})
 	return asgCapacityProvider
 }
@@ -0,0 +1,97 @@
+package containerpatterns
+
+import (
+	"testing"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2/assertions"
+	ec2 "github.com/aws/aws-cdk-go/awscdk/v2/awsec2"
+	ecs "github.com/aws/aws-cdk-go/awscdk/v2/awsecs"
+	elbv2 "github.com/aws/aws-cdk-go/awscdk/v2/awselasticloadbalancingv2"
+	"github.com/aws/jsii-runtime-go"
+)
+
+func TestBridgeServiceOnComputeReachesContainerInstances(t *testing.T) {
+	stack := newTestStack()
+	compute := NewContainerCompute(stack, jsii.String("Compute"), &ContainerComputeProps{
+		Vpc:     ec2.NewVpc(stack, jsii.String("Vpc"), &ec2.VpcProps{}),
+		Cluster: ContainerComputeClusterProps{Name: "cluster", IsAsgCapacityProviderEnabled: true},
+		AsgCapacityProviders: []AutoscalinGroupCapacityProviders{{
+			AutoScalingGroup: ContainerComputeAsgProps{Name: "asg", MinCapacity: 1, MaxCapacity: 2, InstanceClass: ec2.InstanceClass_T3, InstanceSize: ec2.InstanceSize_MEDIUM},
+			CapacityProvider: ContainerComputeAsgCapacityProviderProps{Name: "capacity"},
+		}},
+		LoadBalancer:                 ContainerComputeLoadBalancerProps{Name: "alb", ListenerCertificateArn: "arn:aws:acm:us-east-1:123456789012:certificate/test"},
+		IsNetworkLoadBalancerEnabled: true,
+		NetworkLoadBalancer: ContainerComputeNetworkLoadBalancerProps{Name: "nlb", Listeners: []NetworkListenerProps{
+			{Name: "mqtt", Port: 1883, Protocol: elbv2.Protocol_TCP, TargetType: elbv2.TargetType_INSTANCE},
+		}},
+		CloudmapNamespace: ContainerComputeCloudmapNamespaceProps{Name: "test.local"},
+	})
+
+	NewLoadBalancedEc2Service(stack, jsii.String("Service"), &LoadBalancedEc2ServiceProps{
+		Compute:      compute,
+		LogGroupName: "service",
+		TaskDefinition: TaskDefinition{
+			FamilyName:  "service",
+			NetworkMode: TASK_DEFINTION_NETWORK_MODE_BRIDGE,
+			ApplicationContainers: []ContainerDefinition{{
+				ContainerName: "app", Image: "nginx", ImageTag: "1", RegistryType: CONTAINER_DEFINITION_REGISTRY_OTHERS, IsEssential: true, Memory: 256,
+				PortMappings: []ecs.PortMapping{{ContainerPort: jsii.Number(80)}, {ContainerPort: jsii.Number(1883)}},
+			}},
+			IsFileSystemEnabled: true,
+			FileSystem:          FileSystemProps{AccessPoints: []FileSystemAccessPointProps{{ContainerName: "app", ContainerPath: "/data"}}},
+		},
+		IsLoadBalancerEnabled:     true,
+		LoadBalancer:              LoadBalancerProps{LoadBalancerHealthCheckPath: "/"},
+		LoadBalancerListener:      LoadBalancerListenerProps{RulePriority: 1, PathCondition: "/*"},
+		LoadBalancerTargetOptions: ecs.LoadBalancerTargetOptions{ContainerName: jsii.String("app"), ContainerPort: jsii.Number(80)},
+		NetworkLoadBalancerTargets: []NetworkLoadBalancerTargetProps{
+			{TargetGroup: compute.NetworkTargetGroups()["mqtt"], ContainerName: "app", ContainerPort: 1883},
+		},
+	})
+
+	template := assertions.Template_FromStack(stack, nil)
+	instanceSecurityGroup := map[string]interface{}{"Fn::GetAtt": []interface{}{logicalIdOf(t, template, "AWS::EC2::SecurityGroup", map[string]interface{}{"GroupName": "asgSecurityGroup"}), "GroupId"}}
+	albSecurityGroup := map[string]interface{}{"Fn::GetAtt": []interface{}{logicalIdOf(t, template, "AWS::EC2::SecurityGroup", map[string]interface{}{"GroupDescription": assertions.Match_StringLikeRegexp(jsii.String("alb"))}), "GroupId"}}
+	vpcCidr := map[string]interface{}{"Fn::GetAtt": []interface{}{logicalIdOf(t, template, "AWS::EC2::VPC", map[string]interface{}{}), "CidrBlock"}}
+
+	// the ALB and the NLB reach the dynamic host ports of the container instances
+	template.HasResourceProperties(jsii.String("AWS::EC2::SecurityGroupIngress"), map[string]interface{}{
+		"IpProtocol":            "tcp",
+		"FromPort":              EPHEMERAL_HOST_PORT_MIN,
+		"ToPort":                EPHEMERAL_HOST_PORT_MAX,
+		"GroupId":               instanceSecurityGroup,
+		"SourceSecurityGroupId": albSecurityGroup,
+	})
+	template.HasResourceProperties(jsii.String("AWS::EC2::SecurityGroup"), map[string]interface{}{
+		"GroupName": "asgSecurityGroup",
+		"SecurityGroupIngress": assertions.Match_ArrayWith(&[]interface{}{assertions.Match_ObjectLike(&map[string]interface{}{
+			"IpProtocol": "tcp",
+			"FromPort":   EPHEMERAL_HOST_PORT_MIN,
+			"ToPort":     EPHEMERAL_HOST_PORT_MAX,
+			"CidrIp":     vpcCidr,
+		})}),
+	})
+	// the container instances mount the file system
+	template.HasResourceProperties(jsii.String("AWS::EC2::SecurityGroupIngress"), map[string]interface{}{
+		"IpProtocol":            "tcp",
+		"FromPort":              2049,
+		"ToPort":                2049,
+		"SourceSecurityGroupId": instanceSecurityGroup,
+	})
+	template.HasResource(jsii.String("AWS::ECS::Service"), map[string]interface{}{
+		"DependsOn": assertions.Match_ArrayWith(&[]interface{}{assertions.Match_StringLikeRegexp(jsii.String("FileSystemEfsMountTarget"))}),
+	})
+}
+
+// logicalIdOf returns the logical id of the only resource of the given type with the given properties
+func logicalIdOf(t *testing.T, template assertions.Template, resourceType string, properties map[string]interface{}) string {
+	t.Helper()
+	resources := template.FindResources(jsii.String(resourceType), map[string]interface{}{"Properties": properties})
+	if len(*resources) != 1 {
+		t.Fatalf("expected one %s with %v, got %d", resourceType, properties, len(*resources))
+	}
+	for logicalId := range *resources {
+		return logicalId
+	}
+	return ""
+}
@@ -0,0 +1,166 @@
+package containerpatterns
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	ec2 "github.com/aws/aws-cdk-go/awscdk/v2/awsec2"
+	ecs "github.com/aws/aws-cdk-go/awscdk/v2/awsecs"
+	efs "github.com/aws/aws-cdk-go/awscdk/v2/awsefs"
+	kms "github.com/aws/aws-cdk-go/awscdk/v2/awskms"
+	"github.com/aws/constructs-go/constructs/v10"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// constants
+const (
+	DEFAULT_FILE_SYSTEM_LIFECYCLE_POLICY efs.LifecyclePolicy = efs.LifecyclePolicy_AFTER_30_DAYS
+	DEFAULT_FILE_SYSTEM_THROUGHPUT_MODE  efs.ThroughputMode  = efs.ThroughputMode_ELASTIC
+	DEFAULT_FILE_SYSTEM_PERFORMANCE_MODE efs.PerformanceMode = efs.PerformanceMode_GENERAL_PURPOSE
+	DEFAULT_FILE_SYSTEM_SUBNET_TYPE      ec2.SubnetType      = ec2.SubnetType_PRIVATE_WITH_EGRESS
+	DEFAULT_ACCESS_POINT_POSIX_ID        string              = "1000"
+	DEFAULT_ACCESS_POINT_PERMISSIONS     string              = "750"
+)
+
+// FileSystemProps creates an encrypted EFS file system for the service. Each access point gets its own volume that is
+// mounted into ContainerName at ContainerPath, tasks mount it with IAM authorization over TLS.
+type FileSystemProps struct {
+	Name                     string
+	KmsKeyArn                string
+	LifecyclePolicy          efs.LifecyclePolicy
+	ThroughputMode           efs.ThroughputMode
+	PerformanceMode          efs.PerformanceMode
+	IsAutomaticBackupEnabled bool
+	IsRetainOnDelete         bool
+	SubnetType               ec2.SubnetType
+	AccessPoints             []FileSystemAccessPointProps
+}
+
+// FileSystemAccessPointProps roots the container's view of the file system at Path, which is created with Uid, Gid
+// and Permissions on first use. Path defaults to /<ContainerName>.
+type FileSystemAccessPointProps struct {
+	ContainerName string
+	ContainerPath string
+	Path          string
+	Uid           string
+	Gid           string
+	Permissions   string
+	IsReadOnly    bool
+}
+
+func createServiceFileSystem(scope constructs.Construct, id *string, props *FileSystemProps, vpc ec2.IVpc) efs.FileSystem {
+	lifecyclePolicy := props.LifecyclePolicy
+	if lifecyclePolicy == "" {
+		lifecyclePolicy = DEFAULT_FILE_SYSTEM_LIFECYCLE_POLICY
+	}
+	throughputMode := props.ThroughputMode
+	if throughputMode == "" {
+		throughputMode = DEFAULT_FILE_SYSTEM_THROUGHPUT_MODE
+	}
+	performanceMode := props.PerformanceMode
+	if performanceMode == "" {
+		performanceMode = DEFAULT_FILE_SYSTEM_PERFORMANCE_MODE
+	}
+	subnetType := props.SubnetType
+	if subnetType == "" {
+		subnetType = DEFAULT_FILE_SYSTEM_SUBNET_TYPE
+	}
+	removalPolicy := awscdk.RemovalPolicy_DESTROY
+	if props.IsRetainOnDelete {
+		removalPolicy = awscdk.RemovalPolicy_RETAIN
+	}
+	var encryptionKey kms.IKey = nil
+	if props.KmsKeyArn != "" {
+		encryptionKey = kms.Key_FromKeyArn(scope, jsii.String(*id+"EncryptionKey"), jsii.String(props.KmsKeyArn))
+	}
+
+	fileSystem := efs.NewFileSystem(scope, id, &efs.FileSystemProps{
+		Vpc:                         vpc,
+		VpcSubnets:                  &ec2.SubnetSelection{SubnetType: subnetType},
+		FileSystemName:              optionalString(props.Name),
+		Encrypted:                   jsii.Bool(true),
+		KmsKey:                      encryptionKey,
+		LifecyclePolicy:             lifecyclePolicy,
+		OutOfInfrequentAccessPolicy: efs.OutOfInfrequentAccessPolicy_AFTER_1_ACCESS,
+		ThroughputMode:              throughputMode,
+		PerformanceMode:             performanceMode,
+		EnableAutomaticBackups:      jsii.Bool(props.IsAutomaticBackupEnabled),
+		RemovalPolicy:               removalPolicy,
+	})
+	return fileSystem
+}
+
+// addFileSystemVolumes creates an access point and volume per entry and mounts them into the containers, so it has to
+// run after the application containers are added. The volumes go after any declared in TaskDefinition.Volumes.
+func addFileSystemVolumes(fileSystem efs.FileSystem, props *FileSystemProps, taskDef ecs.TaskDefinition, isFargate bool) {
+	volumes := []Volume{}
+	for index, accessPointProps := range props.AccessPoints {
+		if accessPointProps.ContainerName == "" || accessPointProps.ContainerPath == "" {
+			panic(fmt.Sprintf("file system access point %d requires ContainerName and ContainerPath", index))
+		}
+		path := accessPointProps.Path
+		if path == "" {
+			path = "/" + accessPointProps.ContainerName
+		}
+		uid := accessPointProps.Uid
+		if uid == "" {
+			uid = DEFAULT_ACCESS_POINT_POSIX_ID
+		}
+		gid := accessPointProps.Gid
+		if gid == "" {
+			gid = DEFAULT_ACCESS_POINT_POSIX_ID
+		}
+		permissions := accessPointProps.Permissions
+		if permissions == "" {
+			permissions = DEFAULT_ACCESS_POINT_PERMISSIONS
+		}
+
+		accessPoint := fileSystem.AddAccessPoint(jsii.String(fmt.Sprintf("AccessPoint%d", index)), &efs.AccessPointOptions{
+			Path: jsii.String(path),
+			CreateAcl: &efs.Acl{
+				OwnerUid:    jsii.String(uid),
+				OwnerGid:    jsii.String(gid),
+				Permissions: jsii.String(permissions),
+			},
+			PosixUser: &efs.PosixUser{
+				Uid: jsii.String(uid),
+				Gid: jsii.String(gid),
+			},
+		})
+
+		volumes = append(volumes, Volume{
+			Name: fmt.Sprintf("%s-efs-%d", accessPointProps.ContainerName, index),
+			Kind: VOLUME_KIND_EFS,
+			Efs: EfsVolumeProps{
+				FileSystemId:               *fileSystem.FileSystemId(),
+				AccessPointId:              *accessPoint.AccessPointId(),
+				IsTransitEncryptionEnabled: true,
+				IsIamAuthorizationEnabled:  true,
+				IsReadOnly:                 accessPointProps.IsReadOnly,
+			},
+		})
+	}
+	addTaskDefinitionVolumes(taskDef, volumes, isFargate)
+
+	for index, accessPointProps := range props.AccessPoints {
+		container := taskDef.FindContainer(jsii.String(accessPointProps.ContainerName))
+		if container == nil {
+			panic(fmt.Sprintf("file system access point %d mounts into unknown container %s", index, accessPointProps.ContainerName))
+		}
+		container.AddMountPoints(&ecs.MountPoint{
+			SourceVolume:  jsii.String(volumes[index].Name),
+			ContainerPath: jsii.String(accessPointProps.ContainerPath),
+			ReadOnly:      jsii.Bool(accessPointProps.IsReadOnly),
+		})
+	}
+}
+
+// allowFileSystemAccess opens NFS on the mount targets to the given security groups only
+func allowFileSystemAccess(fileSystem efs.FileSystem, securityGroups []ec2.ISecurityGroup) {
+	if len(securityGroups) == 0 {
+		panic("file system requires the security groups of the service's tasks")
+	}
+	for _, securityGroup := range securityGroups {
+		fileSystem.Connections().AllowDefaultPortFrom(securityGroup, jsii.String("NFS from service tasks"))
+	}
+}
@@ -0,0 +1,165 @@
+package containerpatterns
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	elb2 "github.com/aws/aws-cdk-go/awscdk/v2/awselasticloadbalancingv2"
+	"github.com/aws/constructs-go/constructs/v10"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// custom types
+type RulePriorityAllocation string
+
+const (
+	RULE_PRIORITY_ALLOCATION_EXPLICIT  RulePriorityAllocation = "EXPLICIT"
+	RULE_PRIORITY_ALLOCATION_AUTOMATIC RulePriorityAllocation = "AUTOMATIC"
+	DEFAULT_RULE_PRIORITY_ALLOCATION   RulePriorityAllocation = RULE_PRIORITY_ALLOCATION_EXPLICIT
+)
+
+// automatically allocated priorities sit above the range teams pick explicit priorities from, so explicit rules are
+// evaluated first
+const (
+	AUTOMATIC_RULE_PRIORITY_MIN float64 = 10000
+	AUTOMATIC_RULE_PRIORITY_MAX float64 = 50000
+)
+
+// listenerRulePriorities tracks the rules of one listener, it is a singleton construct found by the listener's key
+type listenerRulePriorities struct {
+	constructs.Construct
+	explicit  map[float64]string
+	automatic map[string]elb2.ApplicationListenerRule
+}
+
+// ToString returns the path like any construct, jsii only hands the registry back from the tree when it overrides a
+// method.
+func (p *listenerRulePriorities) ToString() *string {
+	return p.Node().Path()
+}
+
+// lookupListenerRulePriorities finds or creates the priorities of the listener. A listener imported by a literal ARN
+// or from an SSM parameter, as ContainerComputeFromParameters does, is tracked across the app, whatever construct or
+// stack imported it. Otherwise the ARN resolves to a reference to the listener resource, which is only meaningful
+// within the listener's stack.
+func lookupListenerRulePriorities(listener elb2.IApplicationListener) *listenerRulePriorities {
+	stack := awscdk.Stack_Of(listener)
+	var scope constructs.Construct = stack
+	var key string
+	switch arn := stack.Resolve(listener.ListenerArn()).(type) {
+	case string:
+		scope = stack.Node().Root().(constructs.Construct)
+		key = arn
+	default:
+		if parameterName := lookupSsmParameterName(stack, arn); parameterName != "" {
+			// the same parameter name only names the same listener within one account and region
+			scope = stack.Node().Root().(constructs.Construct)
+			arn = []interface{}{stack.Resolve(stack.Account()), stack.Resolve(stack.Region()), parameterName}
+		}
+		resolved, err := json.Marshal(arn)
+		if err != nil {
+			panic(fmt.Sprintf("cannot resolve the ARN of listener %s: %v", *listener.Node().Path(), err))
+		}
+		key = string(resolved)
+	}
+
+	hash := fnv.New32a()
+	hash.Write([]byte(key))
+	id := fmt.Sprintf("ListenerRulePriorities%08x", hash.Sum32())
+	if child := scope.Node().TryFindChild(jsii.String(id)); child != nil {
+		return child.(*listenerRulePriorities)
+	}
+
+	priorities := &listenerRulePriorities{
+		explicit:  map[float64]string{},
+		automatic: map[string]elb2.ApplicationListenerRule{},
+	}
+	constructs.NewConstruct_Override(priorities, scope, jsii.String(id))
+	return priorities
+}
+
+// createPrioritizedListenerRule reserves an explicit priority, failing when another rule on the listener holds it, or
+// allocates one. Each allocated rule starts from a slot derived from its construct path and takes the next free one,
+// so priorities stay stable as services come and go. Every rule registered on the listener reallocates the others,
+// which leaves the same priorities whatever order services are created in.
+func createPrioritizedListenerRule(scope constructs.Construct, id *string, listener elb2.IApplicationListener, priority float64, allocation RulePriorityAllocation, props *elb2.ApplicationListenerRuleProps) elb2.ApplicationListenerRule {
+	priorities := lookupListenerRulePriorities(listener)
+	path := *scope.Node().Path() + "/" + *id
+
+	if priority != 0 {
+		if owner, ok := priorities.explicit[priority]; ok {
+			panic(fmt.Sprintf("listener rule %s uses priority %v, which is already taken by %s on the same listener", path, priority, owner))
+		}
+		priorities.explicit[priority] = path
+		props.Priority = jsii.Number(priority)
+		rule := elb2.NewApplicationListenerRule(scope, id, props)
+		priorities.allocate()
+		return rule
+	}
+
+	switch allocation {
+	case "", RULE_PRIORITY_ALLOCATION_EXPLICIT:
+		panic("listener rule " + path + " requires a priority, or RULE_PRIORITY_ALLOCATION_AUTOMATIC")
+	case RULE_PRIORITY_ALLOCATION_AUTOMATIC:
+		props.Priority = jsii.Number(AUTOMATIC_RULE_PRIORITY_MIN)
+		rule := elb2.NewApplicationListenerRule(scope, id, props)
+		priorities.automatic[path] = rule
+		priorities.allocate()
+		return rule
+	default:
+		panic(fmt.Sprintf("invalid rule priority allocation %q", allocation))
+	}
+}
+
+// lookupSsmParameterName returns the name of the SSM parameter a resolved value references, or "" when it is not a
+// reference to a parameter of the stack
+func lookupSsmParameterName(stack awscdk.Stack, resolved interface{}) string {
+	reference, ok := resolved.(map[string]interface{})
+	if !ok || len(reference) != 1 || reference["Ref"] == nil {
+		return ""
+	}
+	for _, child := range *stack.Node().Children() {
+		parameter, ok := child.(awscdk.CfnParameter)
+		if !ok || stack.Resolve(parameter.LogicalId()) != reference["Ref"] || !strings.HasPrefix(*parameter.Type(), "AWS::SSM::Parameter::Value") {
+			continue
+		}
+		if name, ok := parameter.Default().(string); ok {
+			return name
+		}
+	}
+	return ""
+}
+
+func (p *listenerRulePriorities) allocate() {
+	slots := AUTOMATIC_RULE_PRIORITY_MAX - AUTOMATIC_RULE_PRIORITY_MIN + 1
+	if float64(len(p.automatic)) > slots {
+		panic(fmt.Sprintf("cannot allocate %d listener rule priorities, only %v are available", len(p.automatic), slots))
+	}
+
+	taken := map[float64]bool{}
+	for priority := range p.explicit {
+		taken[priority] = true
+	}
+	paths := []string{}
+	for path := range p.automatic {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		hash := fnv.New32a()
+		hash.Write([]byte(path))
+		priority := AUTOMATIC_RULE_PRIORITY_MIN + float64(hash.Sum32()%uint32(slots))
+		for taken[priority] {
+			priority++
+			if priority > AUTOMATIC_RULE_PRIORITY_MAX {
+				priority = AUTOMATIC_RULE_PRIORITY_MIN
+			}
+		}
+		taken[priority] = true
+		p.automatic[path].Node().DefaultChild().(elb2.CfnListenerRule).SetPriority(jsii.Number(priority))
+	}
+}
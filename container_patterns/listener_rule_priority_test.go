@@ -0,0 +1,120 @@
+package containerpatterns
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/assertions"
+	ec2 "github.com/aws/aws-cdk-go/awscdk/v2/awsec2"
+	elb2 "github.com/aws/aws-cdk-go/awscdk/v2/awselasticloadbalancingv2"
+	ssm "github.com/aws/aws-cdk-go/awscdk/v2/awsssm"
+	"github.com/aws/constructs-go/constructs/v10"
+	"github.com/aws/jsii-runtime-go"
+)
+
+const testListenerArn = "arn:aws:elasticloadbalancing:us-east-1:123456789012:listener/app/lb/abc/def"
+
+// importTestListener imports the listener under scope, every service imports the listener on its own
+func importTestListener(scope constructs.Construct) elb2.IApplicationListener {
+	return elb2.ApplicationListener_FromApplicationListenerAttributes(scope, jsii.String("Listener"), &elb2.ApplicationListenerAttributes{
+		ListenerArn:   jsii.String(testListenerArn),
+		SecurityGroup: ec2.SecurityGroup_FromSecurityGroupId(scope, jsii.String("ListenerSecurityGroup"), jsii.String("sg-12345"), nil),
+	})
+}
+
+func addTestListenerRule(stack awscdk.Stack, name string, priority float64) {
+	scope := constructs.NewConstruct(stack, jsii.String(name))
+	listener := importTestListener(scope)
+	createServiceListenerRule(scope, jsii.String("Rule"), listener, priority, RULE_PRIORITY_ALLOCATION_AUTOMATIC, &ListenerRuleConditionProps{
+		PathPatterns: []string{"/" + strings.ToLower(name) + "/*"},
+	}, elb2.ListenerAction_FixedResponse(jsii.Number(200), nil))
+}
+
+func templateRulePriorities(stack awscdk.Stack) map[string]float64 {
+	priorities := map[string]float64{}
+	rules := assertions.Template_FromStack(stack, nil).FindResources(jsii.String("AWS::ElasticLoadBalancingV2::ListenerRule"), nil)
+	for logicalId, rule := range *rules {
+		properties := (*rule)["Properties"].(map[string]interface{})
+		priorities[logicalId] = properties["Priority"].(float64)
+	}
+	return priorities
+}
+
+func TestAutomaticRulePrioritiesDoNotDependOnCreationOrder(t *testing.T) {
+	names := []string{"Orders", "Payments", "Users", "Search"}
+
+	forward := newTestStack()
+	for _, name := range names {
+		addTestListenerRule(forward, name, 0)
+	}
+	addTestListenerRule(forward, "Admin", AUTOMATIC_RULE_PRIORITY_MIN)
+
+	reverse := newTestStack()
+	addTestListenerRule(reverse, "Admin", AUTOMATIC_RULE_PRIORITY_MIN)
+	for index := len(names) - 1; index >= 0; index-- {
+		addTestListenerRule(reverse, names[index], 0)
+	}
+
+	forwardPriorities := templateRulePriorities(forward)
+	reversePriorities := templateRulePriorities(reverse)
+	if len(forwardPriorities) != len(names)+1 {
+		t.Fatalf("expected %d listener rules, got %v", len(names)+1, forwardPriorities)
+	}
+	if fmt.Sprint(forwardPriorities) != fmt.Sprint(reversePriorities) {
+		t.Fatalf("priorities depend on creation order: %v and %v", forwardPriorities, reversePriorities)
+	}
+
+	taken := map[float64]string{}
+	for logicalId, priority := range forwardPriorities {
+		if owner, ok := taken[priority]; ok {
+			t.Fatalf("rules %s and %s share priority %v", owner, logicalId, priority)
+		}
+		if priority < AUTOMATIC_RULE_PRIORITY_MIN || priority > AUTOMATIC_RULE_PRIORITY_MAX {
+			t.Fatalf("rule %s got priority %v outside the automatic range", logicalId, priority)
+		}
+		taken[priority] = logicalId
+	}
+}
+
+func TestExplicitRulePriorityClashPanics(t *testing.T) {
+	stack := newTestStack()
+	addTestListenerRule(stack, "Orders", 10)
+
+	defer func() {
+		r := recover()
+		if r == nil || !strings.Contains(fmt.Sprint(r), "already taken by TestStack/Orders/Rule") {
+			t.Fatalf("expected a priority clash, got %v", r)
+		}
+	}()
+	addTestListenerRule(stack, "Payments", 10)
+}
+
+func TestExplicitRulePrioritiesAreTrackedPerApp(t *testing.T) {
+	addTestListenerRule(newTestStack(), "Orders", 10)
+	addTestListenerRule(newTestStack(), "Payments", 10)
+}
+
+func TestExplicitRulePriorityClashPanicsAcrossStacksForListenersFromParameters(t *testing.T) {
+	app := awscdk.NewApp(nil)
+	addRule := func(name string) {
+		stack := awscdk.NewStack(app, jsii.String(name+"Stack"), &awscdk.StackProps{})
+		listener := elb2.ApplicationListener_FromApplicationListenerAttributes(stack, jsii.String("Listener"), &elb2.ApplicationListenerAttributes{
+			ListenerArn:   ssm.StringParameter_ValueForStringParameter(stack, jsii.String("/platform/https-listener-arn"), nil),
+			SecurityGroup: ec2.SecurityGroup_FromSecurityGroupId(stack, jsii.String("ListenerSecurityGroup"), jsii.String("sg-12345"), nil),
+		})
+		createServiceListenerRule(stack, jsii.String("Rule"), listener, 10, RULE_PRIORITY_ALLOCATION_EXPLICIT, &ListenerRuleConditionProps{
+			PathPatterns: []string{"/" + strings.ToLower(name) + "/*"},
+		}, elb2.ListenerAction_FixedResponse(jsii.Number(200), nil))
+	}
+	addRule("Orders")
+
+	defer func() {
+		r := recover()
+		if r == nil || !strings.Contains(fmt.Sprint(r), "already taken by OrdersStack/Rule") {
+			t.Fatalf("expected a priority clash, got %v", r)
+		}
+	}()
+	addRule("Payments")
+}
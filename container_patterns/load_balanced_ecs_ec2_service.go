@@ -5,9 +5,11 @@ import (
 
 	breezewarenetwork "github.com/Breezeware-Technologies/breezeware-aws-cdk-patterns/network"
 	"github.com/aws/aws-cdk-go/awscdk/v2"
+	codedeploy "github.com/aws/aws-cdk-go/awscdk/v2/awscodedeploy"
 	ec2 "github.com/aws/aws-cdk-go/awscdk/v2/awsec2"
 	ecr "github.com/aws/aws-cdk-go/awscdk/v2/awsecr"
 	ecs "github.com/aws/aws-cdk-go/awscdk/v2/awsecs"
+	efs "github.com/aws/aws-cdk-go/awscdk/v2/awsefs"
 	elb2 "github.com/aws/aws-cdk-go/awscdk/v2/awselasticloadbalancingv2"
 	iam "github.com/aws/aws-cdk-go/awscdk/v2/awsiam"
 	cloudwatchlogs "github.com/aws/aws-cdk-go/awscdk/v2/awslogs"
@@ -19,17 +21,16 @@ import (
 
 // custom types
 type (
-	Networkmode                string
-	RegistryType               string
-	LoadBalancerTargetProtocol string
+	Networkmode                  string
+	RegistryType                 string
+	LoadBalancerTargetProtocol   string
+	ContainerDependencyCondition string
 )
 
 // constants
 const (
-	DEFAULT_LOG_RETENTION        cloudwatchlogs.RetentionDays = cloudwatchlogs.RetentionDays_TWO_WEEKS
-	DEFAULT_DOCKER_VOLUME_DRIVER string                       = "rexray/ebs"
-	DEFAULT_DOCKER_VOLUME_TYPE   string                       = "gp2"
-	OTEL_CONTAINER_IMAGE         string                       = "amazon/aws-otel-collector:v0.25.0"
+	DEFAULT_LOG_RETENTION cloudwatchlogs.RetentionDays = cloudwatchlogs.RetentionDays_TWO_WEEKS
+	OTEL_CONTAINER_IMAGE  string                       = "amazon/aws-otel-collector:v0.25.0"
 )
 
 const (
@@ -44,6 +45,14 @@ const (
 	DEFAULT_CONTAINER_DEFINITION_REGISTRY RegistryType = CONTAINER_DEFINITION_REGISTRY_AWS_ECR
 )
 
+const (
+	CONTAINER_DEPENDENCY_CONDITION_START    ContainerDependencyCondition = "START"
+	CONTAINER_DEPENDENCY_CONDITION_HEALTHY  ContainerDependencyCondition = "HEALTHY"
+	CONTAINER_DEPENDENCY_CONDITION_COMPLETE ContainerDependencyCondition = "COMPLETE"
+	CONTAINER_DEPENDENCY_CONDITION_SUCCESS  ContainerDependencyCondition = "SUCCESS"
+	DEFAULT_CONTAINER_DEPENDENCY_CONDITION  ContainerDependencyCondition = CONTAINER_DEPENDENCY_CONDITION_START
+)
+
 const (
 	LOAD_BALANCER_TARGET_PROTOCOL_TCP     LoadBalancerTargetProtocol = "TCP"
 	LOAD_BALANCER_TARGET_PROTOCOL_UDP     LoadBalancerTargetProtocol = "UDP"
@@ -52,18 +61,27 @@ const (
 
 type LoadBalancedEc2ServiceProps struct {
 	Cluster                    ClusterProps
+	Compute                    ServiceCompute
 	LogGroupName               string
+	Logging                    LoggingProps
 	TaskDefinition             TaskDefinition
 	IsTracingEnabled           bool
 	DesiredTaskCount           float64
+	IsAutoScalingEnabled       bool
+	AutoScaling                ServiceAutoScalingProps
 	CapacityProviderStrategies []string
 	IsServiceDiscoveryEnabled  bool
 	ServiceDiscovery           ServiceDiscoveryProps
+	// Deprecated: RoutePriority is not used, set LoadBalancerListener.RulePriority instead.
 	RoutePriority              float64
 	IsLoadBalancerEnabled      bool
 	LoadBalancer               LoadBalancerProps
 	LoadBalancerListener       LoadBalancerListenerProps
 	LoadBalancerTargetOptions  ecs.LoadBalancerTargetOptions
+	NetworkLoadBalancerTargets []NetworkLoadBalancerTargetProps
+	Deployment                 ServiceDeploymentProps
+	DeploymentController       DeploymentController
+	BlueGreenDeployment        BlueGreenDeploymentProps
 }
 
 type ClusterProps struct {
@@ -80,6 +98,8 @@ type TaskDefinition struct {
 	ApplicationContainers []ContainerDefinition
 	RequiresVolume        bool
 	Volumes               []Volume
+	IsFileSystemEnabled   bool
+	FileSystem            FileSystemProps
 }
 
 type EnvironmentFile struct {
@@ -88,23 +108,39 @@ type EnvironmentFile struct {
 }
 
 type ContainerDefinition struct {
-	ContainerName            string
-	Image                    string
-	RegistryType             RegistryType
-	ImageTag                 string
-	IsEssential              bool
-	Commands                 []string
-	EntryPointCommands       []string
-	Cpu                      float64
-	Memory                   float64
-	PortMappings             []ecs.PortMapping
-	EnvironmentFileObjectKey string
-	VolumeMountPoint         []ecs.MountPoint
+	ContainerName             string
+	Image                     string
+	RegistryType              RegistryType
+	ImageTag                  string
+	IsEssential               bool
+	Commands                  []string
+	EntryPointCommands        []string
+	Cpu                       float64
+	Memory                    float64
+	PortMappings              []ecs.PortMapping
+	Environment               map[string]string
+	EnvironmentFileObjectKey  string
+	EnvironmentFileObjectKeys []string
+	Secrets                   []ContainerSecret
+	VolumeMountPoint          []ecs.MountPoint
+	HealthCheck               ContainerHealthCheck
+	StartTimeoutSeconds       float64
+	StopTimeoutSeconds        float64
+	DependsOn                 []ContainerDependency
+	Logging                   LoggingProps
 }
 
-type Volume struct {
-	Name string
-	Size string
+type ContainerHealthCheck struct {
+	Command            []string
+	IntervalSeconds    float64
+	TimeoutSeconds     float64
+	Retries            float64
+	StartPeriodSeconds float64
+}
+
+type ContainerDependency struct {
+	ContainerName string
+	Condition     ContainerDependencyCondition
 }
 
 type ServiceDiscoveryProps struct {
@@ -119,23 +155,43 @@ type LoadBalancerProps struct {
 	LoadBalancerListenerArn     string
 	LoadBalancerSecurityGroupId string
 	LoadBalancerHealthCheckPath string
+	HealthCheck                 TargetGroupHealthCheckProps
+	TargetProtocol              elb2.ApplicationProtocol
+	TargetProtocolVersion       elb2.ApplicationProtocolVersion
+	SlowStartSeconds            float64
+	Stickiness                  TargetGroupStickinessProps
+	LoadBalancingAlgorithm      elb2.TargetGroupLoadBalancingAlgorithmType
 }
 
+// LoadBalancerListenerProps configures the rule forwarding to the service, HostCondition and PathCondition are
+// added to Conditions. Rules are additional rules with their own priorities and actions.
+//
+// Two rules on the same listener cannot share an explicit priority. With RULE_PRIORITY_ALLOCATION_AUTOMATIC, rules
+// without a priority get one allocated from AUTOMATIC_RULE_PRIORITY_MIN up, behind the explicit priorities.
+// Allocated rules are evaluated in no particular order among themselves, so their conditions should not overlap.
 type LoadBalancerListenerProps struct {
-	RulePriority  float64
-	PathCondition string
-	HostCondition string
+	RulePriority       float64
+	PriorityAllocation RulePriorityAllocation
+	PathCondition      string
+	HostCondition      string
+	Conditions         ListenerRuleConditionProps
+	Rules              []ListenerRuleProps
 }
 
 type loadBalancedEc2Service struct {
 	constructs.Construct
-	logGroup   cloudwatchlogs.LogGroup
-	ec2Service ecs.Ec2Service
+	logGroup        cloudwatchlogs.ILogGroup
+	ec2Service      ecs.Ec2Service
+	fileSystem      efs.IFileSystem
+	deploymentGroup codedeploy.IEcsDeploymentGroup
 }
 
 type LoadBalancedEc2Service interface {
+	// LogGroup is nil when Logging.ExistingLogGroupName imports the log group
 	LogGroup() cloudwatchlogs.LogGroup
 	Service() ecs.Ec2Service
+	FileSystem() efs.IFileSystem
+	DeploymentGroup() codedeploy.IEcsDeploymentGroup
 }
 
 func (s *loadBalancedEc2Service) Service() ecs.Ec2Service {
@@ -143,31 +199,22 @@ func (s *loadBalancedEc2Service) Service() ecs.Ec2Service {
 }
 
 func (s *loadBalancedEc2Service) LogGroup() cloudwatchlogs.LogGroup {
-	return s.logGroup
+	logGroup, _ := s.logGroup.(cloudwatchlogs.LogGroup)
+	return logGroup
+}
+
+func (s *loadBalancedEc2Service) FileSystem() efs.IFileSystem {
+	return s.fileSystem
+}
+
+func (s *loadBalancedEc2Service) DeploymentGroup() codedeploy.IEcsDeploymentGroup {
+	return s.deploymentGroup
 }
 
 func NewLoadBalancedEc2Service(scope constructs.Construct, id *string, props *LoadBalancedEc2ServiceProps) LoadBalancedEc2Service {
 	this := constructs.NewConstruct(scope, id)
 
-	var taskPolicyDocument iam.PolicyDocument = nil
-	if props.TaskDefinition.TaskPolicy != nil {
-
-		taskPolicyDocument = props.TaskDefinition.TaskPolicy
-		if props.IsTracingEnabled {
-			taskPolicyDocument.AddStatements(
-				createTaskContainerDefaultXrayPolciyStatement(),
-			)
-		}
-	} else {
-		if props.IsTracingEnabled {
-			taskPolicyDocument = iam.NewPolicyDocument(&iam.PolicyDocumentProps{
-				AssignSids: jsii.Bool(true),
-				Statements: &[]iam.PolicyStatement{
-					createTaskContainerDefaultXrayPolciyStatement(),
-				},
-			})
-		}
-	}
+	taskPolicyDocument := createTaskPolicyDocument(props.TaskDefinition.TaskPolicy, props.IsTracingEnabled)
 
 	var networkMode ecs.NetworkMode = DEFAULT_TASK_DEFINITION_NETWORK_MODE
 	var loadBalancedServiceTargetType elb2.TargetType = elb2.TargetType_IP
@@ -179,93 +226,22 @@ func NewLoadBalancedEc2Service(scope constructs.Construct, id *string, props *Lo
 		loadBalancedServiceTargetType = elb2.TargetType_INSTANCE
 	}
 
-	var taskRole iam.Role = nil
-	if taskPolicyDocument != nil {
-		taskRole = iam.NewRole(this, jsii.String("TaskRole"), &iam.RoleProps{
-			AssumedBy: iam.NewServicePrincipal(jsii.String("ecs-tasks."+*awscdk.Aws_URL_SUFFIX()), &iam.ServicePrincipalOpts{}),
-			InlinePolicies: &map[string]iam.PolicyDocument{
-				*jsii.String("DefaultPolicy"): taskPolicyDocument,
-			},
-		})
-	}
-
 	taskDef := ecs.NewEc2TaskDefinition(this, jsii.String("Ec2TaskDefinition"), &ecs.Ec2TaskDefinitionProps{
-		Family:      jsii.String(props.TaskDefinition.FamilyName),
-		NetworkMode: networkMode,
-		ExecutionRole: iam.NewRole(this, jsii.String("ExecutionRole"), &iam.RoleProps{
-			AssumedBy: iam.NewServicePrincipal(jsii.String("ecs-tasks."+*awscdk.Aws_URL_SUFFIX()), &iam.ServicePrincipalOpts{}),
-			InlinePolicies: &map[string]iam.PolicyDocument{
-				*jsii.String("DefaultPolicy"): iam.NewPolicyDocument(
-					&iam.PolicyDocumentProps{
-						AssignSids: jsii.Bool(true),
-						Statements: &[]iam.PolicyStatement{
-							iam.NewPolicyStatement(
-								&iam.PolicyStatementProps{
-									Actions: &[]*string{
-										jsii.String("s3:GetBucketLocation"),
-									},
-									Effect: iam.Effect_ALLOW,
-									Resources: &[]*string{
-										&props.TaskDefinition.EnvironmentFile.BucketArn,
-									},
-								},
-							),
-						},
-					},
-				),
-			},
-		}),
-		TaskRole: taskRole,
+		Family:        jsii.String(props.TaskDefinition.FamilyName),
+		NetworkMode:   networkMode,
+		ExecutionRole: createTaskExecutionRole(this, jsii.String("ExecutionRole")),
+		TaskRole:      createTaskRole(this, jsii.String("TaskRole"), taskPolicyDocument),
 	})
 
+	var ebsVolume *Volume = nil
 	if props.TaskDefinition.RequiresVolume {
-		for _, volume := range props.TaskDefinition.Volumes {
-			var vol ecs.Volume = ecs.Volume{
-				Name: jsii.String(volume.Name),
-				DockerVolumeConfiguration: &ecs.DockerVolumeConfiguration{
-					Driver:        jsii.String(DEFAULT_DOCKER_VOLUME_DRIVER),
-					Scope:         ecs.Scope_SHARED,
-					Autoprovision: jsii.Bool(true),
-					DriverOpts: &map[string]*string{
-						"volumetype": jsii.String(DEFAULT_DOCKER_VOLUME_TYPE),
-						"size":       jsii.String(volume.Size),
-					},
-				},
-			}
-			taskDef.AddVolume(&vol)
-		}
+		ebsVolume = addTaskDefinitionVolumes(taskDef, props.TaskDefinition.Volumes, false)
 	}
 
 	// Creates a CloudWatch Log Group for each service
-	logGroup := cloudwatchlogs.NewLogGroup(this, jsii.String("LogGroup"), &cloudwatchlogs.LogGroupProps{
-		LogGroupName: jsii.String(props.LogGroupName),
-		Retention:    DEFAULT_LOG_RETENTION,
-	})
-	logGroup.ApplyRemovalPolicy(awscdk.RemovalPolicy_DESTROY)
+	logGroup := createServiceLogGroup(this, jsii.String("LogGroup"), props.LogGroupName, &props.Logging)
 
-	for index, containerDef := range props.TaskDefinition.ApplicationContainers {
-		// update task definition with statements providing container the acces to specific environment files in th S3 bucket
-		taskDef.AddToExecutionRolePolicy(
-			createEnvironmentFileObjectReadOnlyAccessPolicyStatement(
-				props.TaskDefinition.EnvironmentFile.BucketArn,
-				containerDef.EnvironmentFileObjectKey),
-		)
-		// creates container definition for the task definition
-		cd := configureContainerToTaskDefinition(
-			this,
-			"Container"+strconv.FormatInt(int64(index), 10),
-			containerDef,
-			taskDef,
-			s3.Bucket_FromBucketName(
-				this,
-				jsii.String("EnvironmentFileBucket"),
-				jsii.String(props.TaskDefinition.EnvironmentFile.BucketName),
-			),
-			logGroup,
-			props.IsTracingEnabled,
-		)
-		cd.AddMountPoints(convertContainerVolumeMountPoints(containerDef.VolumeMountPoint)...)
-	}
+	addApplicationContainersToTaskDefinition(this, &props.TaskDefinition, taskDef, &props.Logging, logGroup, props.IsTracingEnabled)
 
 	var capacityProviderStrategies []*ecs.CapacityProviderStrategy = []*ecs.CapacityProviderStrategy{}
 	for _, cps := range props.CapacityProviderStrategies {
@@ -273,126 +249,329 @@ func NewLoadBalancedEc2Service(scope constructs.Construct, id *string, props *Lo
 		capacityProviderStrategies = append(capacityProviderStrategies, &capacityProviderStrategy)
 	}
 
-	vpc := lookupVpc(this, id, &props.Cluster.Vpc)
-	var cmOpts *ecs.CloudMapOptions = nil
-	if props.IsServiceDiscoveryEnabled {
-		cmOpts = &ecs.CloudMapOptions{
-			DnsTtl:            awscdk.Duration_Minutes(jsii.Number(1)),
-			DnsRecordType:     servicediscovery.DnsRecordType_A,
-			ContainerPort:     jsii.Number(props.ServiceDiscovery.ServicePort),
-			Name:              jsii.String(props.ServiceDiscovery.ServiceName),
-			CloudMapNamespace: getCloudMapNamespaceService(this, props.ServiceDiscovery),
-		}
+	if props.DeploymentController == DEPLOYMENT_CONTROLLER_CODE_DEPLOY && !props.IsLoadBalancerEnabled {
+		panic("CodeDeploy deployment controller requires the service to be load balanced")
+	}
+	// CodeDeploy moves production traffic to the green target group, a request count policy would keep tracking blue
+	if props.DeploymentController == DEPLOYMENT_CONTROLLER_CODE_DEPLOY && props.IsAutoScalingEnabled && props.AutoScaling.RequestCountPerTarget.TargetValue > 0 {
+		panic("request count auto scaling cannot be used with the CodeDeploy deployment controller")
 	}
+	// ECS only attaches task EBS volumes for services using the ECS deployment controller
+	if props.DeploymentController == DEPLOYMENT_CONTROLLER_CODE_DEPLOY && ebsVolume != nil {
+		panic("CodeDeploy deployment controller cannot be used with the EBS volume " + ebsVolume.Name)
+	}
+
+	cluster, vpc := resolveServiceCluster(this, props.Compute, &props.Cluster)
 	ec2Service := ecs.NewEc2Service(this, jsii.String("Ec2Service"), &ecs.Ec2ServiceProps{
-		Cluster: ecs.Cluster_FromClusterAttributes(this, jsii.String("Cluster"), &ecs.ClusterAttributes{
-			ClusterName:    jsii.String(props.Cluster.ClusterName),
-			Vpc:            vpc,
-			SecurityGroups: &props.Cluster.SecurityGroups,
-		}),
+		Cluster:                    cluster,
 		CapacityProviderStrategies: &capacityProviderStrategies,
 		TaskDefinition:             taskDef,
 		DesiredCount:               &props.DesiredTaskCount,
-		CircuitBreaker: &ecs.DeploymentCircuitBreaker{
-			Rollback: jsii.Bool(true),
-		},
+		MinHealthyPercent:          optionalNumber(props.Deployment.MinHealthyPercent),
+		MaxHealthyPercent:          optionalNumber(props.Deployment.MaxHealthyPercent),
+		HealthCheckGracePeriod:     optionalDurationSeconds(props.Deployment.HealthCheckGracePeriodSeconds),
+		DeploymentController:       createServiceDeploymentController(props.DeploymentController),
+		CircuitBreaker:             createServiceCircuitBreaker(props.DeploymentController, props.Deployment.CircuitBreaker),
 		PlacementStrategies: &[]ecs.PlacementStrategy{
 			ecs.PlacementStrategy_PackedByMemory(),
 		},
-		CloudMapOptions:      cmOpts,
+		CloudMapOptions:      createServiceCloudMapOptions(this, props.IsServiceDiscoveryEnabled, &props.ServiceDiscovery, props.Compute),
 		PropagateTags:        ecs.PropagatedTagSource_SERVICE,
 		EnableECSManagedTags: jsii.Bool(true),
 	})
 
+	configureServiceDeploymentAlarms(ec2Service, props.DeploymentController, &props.Deployment)
+
+	if ebsVolume != nil {
+		configureServiceEbsVolume(this, ec2Service, ebsVolume)
+	}
+
+	var fileSystem efs.FileSystem = nil
+	if props.TaskDefinition.IsFileSystemEnabled {
+		fileSystem = createServiceFileSystem(this, jsii.String("FileSystem"), &props.TaskDefinition.FileSystem, vpc)
+		addFileSystemVolumes(fileSystem, &props.TaskDefinition.FileSystem, taskDef, false)
+		// tasks can only mount the file system once its mount targets exist
+		ec2Service.Node().AddDependency(fileSystem.MountTargetsAvailable())
+		// bridge mode tasks share the network of the container instances
+		if networkMode == ecs.NetworkMode_AWS_VPC {
+			allowFileSystemAccess(fileSystem, *ec2Service.Connections().SecurityGroups())
+		} else {
+			instanceSecurityGroups := *cluster.Connections().SecurityGroups()
+			if len(instanceSecurityGroups) == 0 {
+				panic("file system of a bridge mode service requires the cluster's container instance security groups, from the Compute's capacity providers or ClusterProps.SecurityGroups")
+			}
+			allowFileSystemAccess(fileSystem, instanceSecurityGroups)
+		}
+	}
+
+	var targetGroup elb2.ApplicationTargetGroup = nil
+	var deploymentGroup codedeploy.EcsDeploymentGroup = nil
 	if props.IsLoadBalancerEnabled {
-		ecsServiceTargetGroup := elb2.NewApplicationTargetGroup(this, jsii.String("ApplicationTargetGroup"), &elb2.ApplicationTargetGroupProps{
-			HealthCheck: &elb2.HealthCheck{
-				Enabled:          jsii.Bool(true),
-				HealthyHttpCodes: jsii.String("200"),
-				Path:             jsii.String(props.LoadBalancer.LoadBalancerHealthCheckPath),
-				Interval:         awscdk.Duration_Seconds(jsii.Number(30)),
-			},
-			TargetType: loadBalancedServiceTargetType,
-			Vpc:        vpc,
-			Protocol:   elb2.ApplicationProtocol_HTTP,
-			Targets: &[]elb2.IApplicationLoadBalancerTarget{
-				ec2Service.LoadBalancerTarget(&props.LoadBalancerTargetOptions),
-			},
-		})
-
-		elb2.NewApplicationListenerRule(this, jsii.String("ALBListenerRule"), &elb2.ApplicationListenerRuleProps{
-			Priority: jsii.Number(props.LoadBalancerListener.RulePriority),
-			Action:   elb2.ListenerAction_Forward(&[]elb2.IApplicationTargetGroup{ecsServiceTargetGroup}, &elb2.ForwardOptions{}),
-			Conditions: &[]elb2.ListenerCondition{
-				elb2.ListenerCondition_HostHeaders(jsii.Strings(props.LoadBalancerListener.HostCondition)),
-				elb2.ListenerCondition_PathPatterns(jsii.Strings(props.LoadBalancerListener.PathCondition)),
-			},
-			Listener: elb2.ApplicationListener_FromApplicationListenerAttributes(this, jsii.String("ALBListener"), &elb2.ApplicationListenerAttributes{
-				ListenerArn:   jsii.String(props.LoadBalancer.LoadBalancerListenerArn),
-				SecurityGroup: ec2.SecurityGroup_FromLookupById(this, jsii.String("ALBSecurityGroup"), jsii.String(props.LoadBalancer.LoadBalancerSecurityGroupId)),
-			}),
-		})
+		var listener elb2.IApplicationListener
+		targetGroup, listener = attachServiceToLoadBalancer(
+			this,
+			vpc,
+			loadBalancedServiceTargetType,
+			ec2Service.LoadBalancerTarget(&props.LoadBalancerTargetOptions),
+			resolveServiceListener(this, props.Compute, &props.LoadBalancer),
+			&props.LoadBalancer,
+			&props.LoadBalancerListener,
+			props.Deployment.DeregistrationDelaySeconds,
+		)
+		if props.DeploymentController == DEPLOYMENT_CONTROLLER_CODE_DEPLOY {
+			deploymentGroup = configureServiceBlueGreenDeployment(
+				this,
+				ec2Service,
+				&props.BlueGreenDeployment,
+				vpc,
+				loadBalancedServiceTargetType,
+				&props.LoadBalancer,
+				&props.LoadBalancerListener,
+				targetGroup,
+				listener,
+				props.Deployment.DeregistrationDelaySeconds,
+			)
+		}
+	}
+
+	if len(props.NetworkLoadBalancerTargets) > 0 {
+		if props.DeploymentController == DEPLOYMENT_CONTROLLER_CODE_DEPLOY {
+			panic("network load balancer targets cannot be added to a service using the CodeDeploy deployment controller")
+		}
+		// bridge mode tasks receive traffic on the ports of the container instances
+		connections := ec2Service.Connections()
+		if networkMode != ecs.NetworkMode_AWS_VPC {
+			connections = cluster.Connections()
+			if len(*connections.SecurityGroups()) == 0 {
+				panic("network load balancer targets of a bridge mode service require the cluster's container instance security groups, from the Compute's capacity providers or ClusterProps.SecurityGroups")
+			}
+		}
+		attachServiceToNetworkLoadBalancers(ec2Service, connections, vpc, props.NetworkLoadBalancerTargets, networkMode != ecs.NetworkMode_AWS_VPC)
+	}
+
+	if props.IsAutoScalingEnabled {
+		configureServiceAutoScaling(ec2Service, &props.AutoScaling, targetGroup)
 	}
 
-	return &loadBalancedEc2Service{this, logGroup, ec2Service}
+	return &loadBalancedEc2Service{this, logGroup, ec2Service, fileSystem, deploymentGroup}
 }
 
-func configureContainerToTaskDefinition(scope constructs.Construct, id string, containerDef ContainerDefinition, taskDef ecs.TaskDefinition, taskDefEnvFileBucket s3.IBucket, logGroup cloudwatchlogs.ILogGroup, tracingEnabled bool) ecs.ContainerDefinition {
-	cd := ecs.NewContainerDefinition(scope, jsii.String(id), &ecs.ContainerDefinitionProps{
-		TaskDefinition: taskDef,
-		ContainerName:  &containerDef.ContainerName,
-		Command:        convertContainerCommands(containerDef.Commands),
-		EntryPoint:     convertContainerEntryPointCommands(containerDef.EntryPointCommands),
-		Essential:      jsii.Bool(containerDef.IsEssential),
-		Image:          configureContainerImage(scope, containerDef.RegistryType, containerDef.Image, containerDef.ImageTag),
-		Cpu:            jsii.Number(containerDef.Cpu),
-		MemoryLimitMiB: jsii.Number(containerDef.Memory),
-		EnvironmentFiles: &[]ecs.EnvironmentFile{
-			ecs.AssetEnvironmentFile_FromBucket(taskDefEnvFileBucket, jsii.String(containerDef.EnvironmentFileObjectKey), nil),
+func createTaskPolicyDocument(taskPolicy iam.PolicyDocument, tracingEnabled bool) iam.PolicyDocument {
+	var taskPolicyDocument iam.PolicyDocument = nil
+	if taskPolicy != nil {
+
+		taskPolicyDocument = taskPolicy
+		if tracingEnabled {
+			taskPolicyDocument.AddStatements(
+				createTaskContainerDefaultXrayPolciyStatement(),
+			)
+		}
+	} else {
+		if tracingEnabled {
+			taskPolicyDocument = iam.NewPolicyDocument(&iam.PolicyDocumentProps{
+				AssignSids: jsii.Bool(true),
+				Statements: &[]iam.PolicyStatement{
+					createTaskContainerDefaultXrayPolciyStatement(),
+				},
+			})
+		}
+	}
+	return taskPolicyDocument
+}
+
+func createTaskRole(scope constructs.Construct, id *string, policyDocument iam.PolicyDocument) iam.IRole {
+	if policyDocument == nil {
+		return nil
+	}
+	taskRole := iam.NewRole(scope, id, &iam.RoleProps{
+		AssumedBy: iam.NewServicePrincipal(jsii.String("ecs-tasks."+*awscdk.Aws_URL_SUFFIX()), &iam.ServicePrincipalOpts{}),
+		InlinePolicies: &map[string]iam.PolicyDocument{
+			*jsii.String("DefaultPolicy"): policyDocument,
 		},
-		Logging:      setupContianerAwsLogDriver(logGroup, containerDef.ContainerName),
-		PortMappings: convertContainerPortMappings(containerDef.PortMappings),
 	})
+	return taskRole
+}
 
+func createTaskExecutionRole(scope constructs.Construct, id *string) iam.IRole {
+	executionRole := iam.NewRole(scope, id, &iam.RoleProps{
+		AssumedBy: iam.NewServicePrincipal(jsii.String("ecs-tasks."+*awscdk.Aws_URL_SUFFIX()), &iam.ServicePrincipalOpts{}),
+	})
+	return executionRole
+}
+
+func addApplicationContainersToTaskDefinition(scope constructs.Construct, props *TaskDefinition, taskDef ecs.TaskDefinition, logging *LoggingProps, logGroup cloudwatchlogs.ILogGroup, tracingEnabled bool) {
+	var envFileBucket s3.IBucket = nil
+	var logRouter ecs.FirelensLogRouter = nil
+	containers := map[string]ecs.ContainerDefinition{}
+
+	// a single OTEL collector per task is shared by all application containers
+	var otelContainerDef ecs.ContainerDefinition = nil
 	if tracingEnabled {
-		otelContainerDef := ecs.NewContainerDefinition(scope, jsii.String("OtelContainerDefinition"), &ecs.ContainerDefinitionProps{
-			TaskDefinition: taskDef,
-			ContainerName:  jsii.String("otel-xray"),
-			Image:          ecs.ContainerImage_FromRegistry(jsii.String(OTEL_CONTAINER_IMAGE), &ecs.RepositoryImageProps{}),
-			Cpu:            jsii.Number(256),
-			MemoryLimitMiB: jsii.Number(256),
-			Logging:        setupContianerAwsLogDriver(logGroup, "Otel"),
-			Command: &[]*string{
-				jsii.String("--config=/etc/ecs/ecs-default-config.yaml"),
-			},
-			PortMappings: &[]*ecs.PortMapping{
-				{
-					ContainerPort: jsii.Number(2000),
-					HostPort:      jsii.Number(2000),
-					Protocol:      ecs.Protocol_UDP,
-				},
-				{
-					ContainerPort: jsii.Number(4317),
-					HostPort:      jsii.Number(4317),
-					Protocol:      ecs.Protocol_TCP,
-				},
-				{
-					ContainerPort: jsii.Number(8125),
-					HostPort:      jsii.Number(8125),
-					Protocol:      ecs.Protocol_UDP,
-				},
-			},
-		})
-		cd.AddLink(otelContainerDef, jsii.String("otel-xray"))
-		cd.AddContainerDependencies(&ecs.ContainerDependency{
-			Condition: ecs.ContainerDependencyCondition_START,
-			Container: otelContainerDef,
-		})
+		otelContainerDef = createOtelContainerDefinition(scope, jsii.String("OtelContainerDefinition"), taskDef, logGroup)
+	}
+
+	for index, containerDef := range props.ApplicationContainers {
+		if _, exists := containers[containerDef.ContainerName]; exists {
+			panic("duplicate application container name " + containerDef.ContainerName)
+		}
+		envFileObjectKeys := containerEnvironmentFileObjectKeys(&containerDef)
+		if len(envFileObjectKeys) > 0 && envFileBucket == nil {
+			envFileBucket = lookupEnvironmentFileBucket(scope, jsii.String("EnvironmentFileBucket"), &props.EnvironmentFile)
+			taskDef.AddToExecutionRolePolicy(createEnvironmentFileBucketLocationPolicyStatement(*envFileBucket.BucketArn()))
+		}
+		// update task definition with statements providing container the acces to specific environment files in th S3 bucket
+		for _, key := range envFileObjectKeys {
+			taskDef.AddToExecutionRolePolicy(
+				createEnvironmentFileObjectReadOnlyAccessPolicyStatement(*envFileBucket.BucketArn(), key),
+			)
+		}
+		id := "Container" + strconv.FormatInt(int64(index), 10)
+		containerLogging, containerLogGroup := resolveContainerLogging(scope, id, &containerDef, logging, logGroup)
+		// a single FireLens log router per task serves every container logging through FireLens
+		if containerLogging.Driver == LOG_DRIVER_FIRELENS && logRouter == nil {
+			logRouter = createFireLensLogRouter(&containerLogging.FireLens, taskDef, logGroup)
+		}
+		// creates container definition for the task definition
+		cd := configureContainerToTaskDefinition(
+			scope,
+			id,
+			containerDef,
+			taskDef,
+			convertContainerEnvironmentFiles(envFileBucket, envFileObjectKeys),
+			createContainerLogDriver(scope, id, containerDef.ContainerName, containerLogging, containerLogGroup, taskDef),
+		)
+		cd.AddMountPoints(convertContainerVolumeMountPoints(containerDef.VolumeMountPoint)...)
+		if otelContainerDef != nil {
+			// links are only supported in bridge mode, in awsvpc mode the collector is reachable on localhost
+			if taskDef.NetworkMode() == ecs.NetworkMode_BRIDGE {
+				cd.AddLink(otelContainerDef, jsii.String("otel-xray"))
+			}
+			cd.AddContainerDependencies(&ecs.ContainerDependency{
+				Condition: ecs.ContainerDependencyCondition_START,
+				Container: otelContainerDef,
+			})
+		}
+		containers[containerDef.ContainerName] = cd
+	}
+
+	// dependencies are resolved once every application container exists so they can refer to each other in any order
+	for _, containerDef := range props.ApplicationContainers {
+		for _, dependency := range containerDef.DependsOn {
+			configureContainerDependency(containers, props.ApplicationContainers, &containerDef, &dependency)
+		}
+	}
+}
+
+func configureContainerDependency(containers map[string]ecs.ContainerDefinition, containerDefs []ContainerDefinition, containerDef *ContainerDefinition, dependency *ContainerDependency) {
+	dependencyContainer, ok := containers[dependency.ContainerName]
+	if !ok {
+		panic("container " + containerDef.ContainerName + " depends on unknown container " + dependency.ContainerName)
+	}
+
+	condition := dependency.Condition
+	if condition == "" {
+		condition = DEFAULT_CONTAINER_DEPENDENCY_CONDITION
+	}
+	if condition == CONTAINER_DEPENDENCY_CONDITION_HEALTHY {
+		for _, cd := range containerDefs {
+			if cd.ContainerName == dependency.ContainerName && len(cd.HealthCheck.Command) == 0 {
+				panic("container " + containerDef.ContainerName + " waits for " + dependency.ContainerName + " to be HEALTHY but it has no health check")
+			}
+		}
 	}
 
+	containers[containerDef.ContainerName].AddContainerDependencies(&ecs.ContainerDependency{
+		Container: dependencyContainer,
+		Condition: ecs.ContainerDependencyCondition(condition),
+	})
+}
+
+func createServiceCloudMapOptions(scope constructs.Construct, enabled bool, props *ServiceDiscoveryProps, compute ServiceCompute) *ecs.CloudMapOptions {
+	if !enabled {
+		return nil
+	}
+	cmOpts := &ecs.CloudMapOptions{
+		DnsTtl:            awscdk.Duration_Minutes(jsii.Number(1)),
+		DnsRecordType:     servicediscovery.DnsRecordType_A,
+		ContainerPort:     jsii.Number(props.ServicePort),
+		Name:              jsii.String(props.ServiceName),
+		CloudMapNamespace: resolveServiceCloudMapNamespace(scope, compute, props),
+	}
+	return cmOpts
+}
+
+func attachServiceToLoadBalancer(scope constructs.Construct, vpc ec2.IVpc, targetType elb2.TargetType, target elb2.IApplicationLoadBalancerTarget, listener elb2.IApplicationListener, lbProps *LoadBalancerProps, listenerProps *LoadBalancerListenerProps, deregistrationDelaySeconds float64) (elb2.ApplicationTargetGroup, elb2.IApplicationListener) {
+	ecsServiceTargetGroup := createServiceTargetGroup(scope, jsii.String("ApplicationTargetGroup"), vpc, targetType, lbProps, deregistrationDelaySeconds)
+	ecsServiceTargetGroup.AddTarget(target)
+
+	createServiceListenerRule(
+		scope,
+		jsii.String("ALBListenerRule"),
+		listener,
+		listenerProps.RulePriority,
+		listenerProps.PriorityAllocation,
+		resolveServiceListenerConditions(listenerProps),
+		elb2.ListenerAction_Forward(&[]elb2.IApplicationTargetGroup{ecsServiceTargetGroup}, &elb2.ForwardOptions{}),
+	)
+	addServiceListenerRules(scope, listener, listenerProps.Rules, listenerProps.PriorityAllocation, ecsServiceTargetGroup)
+	return ecsServiceTargetGroup, listener
+}
+
+func configureContainerToTaskDefinition(scope constructs.Construct, id string, containerDef ContainerDefinition, taskDef ecs.TaskDefinition, envFiles *[]ecs.EnvironmentFile, logDriver ecs.LogDriver) ecs.ContainerDefinition {
+	cd := ecs.NewContainerDefinition(scope, jsii.String(id), &ecs.ContainerDefinitionProps{
+		TaskDefinition:   taskDef,
+		ContainerName:    &containerDef.ContainerName,
+		Command:          convertContainerCommands(containerDef.Commands),
+		EntryPoint:       convertContainerEntryPointCommands(containerDef.EntryPointCommands),
+		Essential:        jsii.Bool(containerDef.IsEssential),
+		Image:            configureContainerImage(scope, id+"EcrRepository", containerDef.RegistryType, containerDef.Image, containerDef.ImageTag),
+		Cpu:              optionalNumber(containerDef.Cpu),
+		MemoryLimitMiB:   optionalNumber(containerDef.Memory),
+		Environment:      convertContainerEnvironment(containerDef.Environment),
+		EnvironmentFiles: envFiles,
+		Secrets:          createContainerSecrets(scope, containerDef.ContainerName, containerDef.Secrets, taskDef),
+		Logging:          logDriver,
+		PortMappings:     convertContainerPortMappings(containerDef.PortMappings),
+		HealthCheck:      convertContainerHealthCheck(&containerDef.HealthCheck),
+		StartTimeout:     optionalDurationSeconds(containerDef.StartTimeoutSeconds),
+		StopTimeout:      optionalDurationSeconds(containerDef.StopTimeoutSeconds),
+	})
+
 	return cd
 }
 
+func createOtelContainerDefinition(scope constructs.Construct, id *string, taskDef ecs.TaskDefinition, logGroup cloudwatchlogs.ILogGroup) ecs.ContainerDefinition {
+	otelContainerDef := ecs.NewContainerDefinition(scope, id, &ecs.ContainerDefinitionProps{
+		TaskDefinition: taskDef,
+		ContainerName:  jsii.String("otel-xray"),
+		Image:          ecs.ContainerImage_FromRegistry(jsii.String(OTEL_CONTAINER_IMAGE), &ecs.RepositoryImageProps{}),
+		Cpu:            jsii.Number(256),
+		MemoryLimitMiB: jsii.Number(256),
+		Logging:        setupContianerAwsLogDriver(logGroup, "Otel"),
+		Command: &[]*string{
+			jsii.String("--config=/etc/ecs/ecs-default-config.yaml"),
+		},
+		PortMappings: &[]*ecs.PortMapping{
+			{
+				ContainerPort: jsii.Number(2000),
+				HostPort:      jsii.Number(2000),
+				Protocol:      ecs.Protocol_UDP,
+			},
+			{
+				ContainerPort: jsii.Number(4317),
+				HostPort:      jsii.Number(4317),
+				Protocol:      ecs.Protocol_TCP,
+			},
+			{
+				ContainerPort: jsii.Number(8125),
+				HostPort:      jsii.Number(8125),
+				Protocol:      ecs.Protocol_UDP,
+			},
+		},
+	})
+	return otelContainerDef
+}
+
 func convertContainerCommands(cmds []string) *[]*string {
 	commands := []*string{}
 	for _, cmd := range cmds {
@@ -409,10 +588,65 @@ func convertContainerEntryPointCommands(cmds []string) *[]*string {
 	return &entryPointCmds
 }
 
+func convertContainerHealthCheck(hc *ContainerHealthCheck) *ecs.HealthCheck {
+	if len(hc.Command) == 0 {
+		return nil
+	}
+	healthCheck := &ecs.HealthCheck{
+		Command:     jsii.Strings(hc.Command...),
+		Interval:    optionalDurationSeconds(hc.IntervalSeconds),
+		Timeout:     optionalDurationSeconds(hc.TimeoutSeconds),
+		Retries:     optionalNumber(hc.Retries),
+		StartPeriod: optionalDurationSeconds(hc.StartPeriodSeconds),
+	}
+	return healthCheck
+}
+
+func convertContainerEnvironment(env map[string]string) *map[string]*string {
+	if len(env) == 0 {
+		return nil
+	}
+	environment := map[string]*string{}
+	for key, value := range env {
+		environment[key] = jsii.String(value)
+	}
+	return &environment
+}
+
+// containerEnvironmentFileObjectKeys returns the S3 object keys of every environment file of the container
+func containerEnvironmentFileObjectKeys(containerDef *ContainerDefinition) []string {
+	keys := []string{}
+	if containerDef.EnvironmentFileObjectKey != "" {
+		keys = append(keys, containerDef.EnvironmentFileObjectKey)
+	}
+	return append(keys, containerDef.EnvironmentFileObjectKeys...)
+}
+
+func convertContainerEnvironmentFiles(bucket s3.IBucket, keys []string) *[]ecs.EnvironmentFile {
+	if len(keys) == 0 {
+		return nil
+	}
+	envFiles := []ecs.EnvironmentFile{}
+	for _, key := range keys {
+		envFiles = append(envFiles, ecs.EnvironmentFile_FromBucket(bucket, jsii.String(key), nil))
+	}
+	return &envFiles
+}
+
+func lookupEnvironmentFileBucket(scope constructs.Construct, id *string, props *EnvironmentFile) s3.IBucket {
+	if props.BucketName == "" && props.BucketArn == "" {
+		panic("container environment files require TaskDefinition.EnvironmentFile.BucketName or BucketArn")
+	}
+	if props.BucketArn != "" {
+		return s3.Bucket_FromBucketArn(scope, id, jsii.String(props.BucketArn))
+	}
+	return s3.Bucket_FromBucketName(scope, id, jsii.String(props.BucketName))
+}
+
 func convertContainerPortMappings(pm []ecs.PortMapping) *[]*ecs.PortMapping {
 	portMapping := []*ecs.PortMapping{}
-	for _, mapping := range pm {
-		portMapping = append(portMapping, &mapping)
+	for index := range pm {
+		portMapping = append(portMapping, &pm[index])
 	}
 	return &portMapping
 
@@ -420,15 +654,15 @@ func convertContainerPortMappings(pm []ecs.PortMapping) *[]*ecs.PortMapping {
 
 func convertContainerVolumeMountPoints(pm []ecs.MountPoint) []*ecs.MountPoint {
 	mountPoints := []*ecs.MountPoint{}
-	for _, mount := range pm {
-		mountPoints = append(mountPoints, &mount)
+	for index := range pm {
+		mountPoints = append(mountPoints, &pm[index])
 	}
 	return mountPoints
 }
 
-func configureContainerImage(scope constructs.Construct, registryType RegistryType, image string, tag string) ecs.ContainerImage {
+func configureContainerImage(scope constructs.Construct, id string, registryType RegistryType, image string, tag string) ecs.ContainerImage {
 	if registryType == CONTAINER_DEFINITION_REGISTRY_AWS_ECR {
-		return ecs.ContainerImage_FromEcrRepository(ecr.Repository_FromRepositoryName(scope, jsii.String("EcrRepository"), jsii.String(image)), jsii.String(tag))
+		return ecs.ContainerImage_FromEcrRepository(ecr.Repository_FromRepositoryName(scope, jsii.String(id), jsii.String(image)), jsii.String(tag))
 	} else {
 		return ecs.ContainerImage_FromRegistry(jsii.String(image+":"+tag), &ecs.RepositoryImageProps{})
 	}
@@ -438,19 +672,10 @@ func setupContianerAwsLogDriver(logGroup cloudwatchlogs.ILogGroup, prefix string
 	logDriver := ecs.AwsLogDriver_AwsLogs(&ecs.AwsLogDriverProps{
 		LogGroup:     logGroup,
 		StreamPrefix: jsii.String(prefix),
-		// LogRetention: DEFAULT_LOG_RETENTION,
 	})
 	return logDriver
 }
 
-func lookupVpc(scope constructs.Construct, id *string, props *breezewarenetwork.VpcProps) ec2.IVpc {
-	vpc := ec2.Vpc_FromLookup(scope, jsii.String("Vpc"), &ec2.VpcLookupOptions{
-		VpcId:     jsii.String(props.Id),
-		IsDefault: jsii.Bool(props.IsDefault),
-	})
-	return vpc
-}
-
 func createServiceCapacityProviderStrategy(name string) ecs.CapacityProviderStrategy {
 	capacityProviderStrategy := ecs.CapacityProviderStrategy{
 		CapacityProvider: jsii.String(name),
@@ -460,6 +685,22 @@ func createServiceCapacityProviderStrategy(name string) ecs.CapacityProviderStra
 	return capacityProviderStrategy
 }
 
+func createEnvironmentFileBucketLocationPolicyStatement(bucket string) iam.PolicyStatement {
+	policy := iam.NewPolicyStatement(
+		&iam.PolicyStatementProps{
+			Effect: iam.Effect_ALLOW,
+			Actions: &[]*string{
+				jsii.String("s3:GetBucketLocation"),
+			},
+			Resources: &[]*string{
+				jsii.String(bucket),
+			},
+		},
+	)
+
+	return policy
+}
+
 func createEnvironmentFileObjectReadOnlyAccessPolicyStatement(bucket string, key string) iam.PolicyStatement {
 
 	policy := iam.NewPolicyStatement(
@@ -0,0 +1,315 @@
+package containerpatterns
+
+import (
+	"fmt"
+
+	ec2 "github.com/aws/aws-cdk-go/awscdk/v2/awsec2"
+	ecs "github.com/aws/aws-cdk-go/awscdk/v2/awsecs"
+	efs "github.com/aws/aws-cdk-go/awscdk/v2/awsefs"
+	elb2 "github.com/aws/aws-cdk-go/awscdk/v2/awselasticloadbalancingv2"
+	cloudwatchlogs "github.com/aws/aws-cdk-go/awscdk/v2/awslogs"
+	"github.com/aws/constructs-go/constructs/v10"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// custom types
+type (
+	FargateCapacityProvider string
+	FargateCpuArchitecture  string
+)
+
+const (
+	FARGATE_CAPACITY_PROVIDER_FARGATE      FargateCapacityProvider = "FARGATE"
+	FARGATE_CAPACITY_PROVIDER_FARGATE_SPOT FargateCapacityProvider = "FARGATE_SPOT"
+	DEFAULT_FARGATE_CAPACITY_PROVIDER      FargateCapacityProvider = FARGATE_CAPACITY_PROVIDER_FARGATE
+)
+
+const (
+	FARGATE_CPU_ARCHITECTURE_X86_64  FargateCpuArchitecture = "X86_64"
+	FARGATE_CPU_ARCHITECTURE_ARM64   FargateCpuArchitecture = "ARM64"
+	DEFAULT_FARGATE_CPU_ARCHITECTURE FargateCpuArchitecture = FARGATE_CPU_ARCHITECTURE_X86_64
+)
+
+const (
+	DEFAULT_FARGATE_TASK_CPU         float64                    = 256
+	DEFAULT_FARGATE_PLATFORM_VERSION ecs.FargatePlatformVersion = ecs.FargatePlatformVersion_LATEST
+	DEFAULT_FARGATE_SUBNET_TYPE      ec2.SubnetType             = ec2.SubnetType_PRIVATE_WITH_EGRESS
+)
+
+// valid task memory (MiB) values for each Fargate task CPU size
+var fargateTaskMemoryByCpu = map[float64][]float64{
+	256:   {512, 1024, 2048},
+	512:   {1024, 2048, 3072, 4096},
+	1024:  {2048, 3072, 4096, 5120, 6144, 7168, 8192},
+	2048:  rangeOfMemory(4096, 16384, 1024),
+	4096:  rangeOfMemory(8192, 30720, 1024),
+	8192:  rangeOfMemory(16384, 61440, 4096),
+	16384: rangeOfMemory(32768, 122880, 8192),
+}
+
+type LoadBalancedFargateServiceProps struct {
+	Cluster                    ClusterProps
+	Compute                    ServiceCompute
+	LogGroupName               string
+	Logging                    LoggingProps
+	TaskDefinition             TaskDefinition
+	TaskSize                   FargateTaskSizeProps
+	IsTracingEnabled           bool
+	DesiredTaskCount           float64
+	IsAutoScalingEnabled       bool
+	AutoScaling                ServiceAutoScalingProps
+	PlatformVersion            ecs.FargatePlatformVersion
+	CapacityProviderStrategies []FargateCapacityProviderStrategy
+	Network                    FargateNetworkProps
+	IsServiceDiscoveryEnabled  bool
+	ServiceDiscovery           ServiceDiscoveryProps
+	IsLoadBalancerEnabled      bool
+	LoadBalancer               LoadBalancerProps
+	LoadBalancerListener       LoadBalancerListenerProps
+	LoadBalancerTargetOptions  ecs.LoadBalancerTargetOptions
+	NetworkLoadBalancerTargets []NetworkLoadBalancerTargetProps
+}
+
+type FargateTaskSizeProps struct {
+	Cpu                 float64
+	Memory              float64
+	EphemeralStorageGiB float64
+	CpuArchitecture     FargateCpuArchitecture
+}
+
+// FargateCapacityProviderStrategy splits tasks above Base by Weight, without a Weight on any strategy they are
+// weighted equally.
+type FargateCapacityProviderStrategy struct {
+	CapacityProvider FargateCapacityProvider
+	Weight           float64
+	Base             float64
+}
+
+type FargateNetworkProps struct {
+	SubnetType     ec2.SubnetType
+	SubnetIds      []string
+	SecurityGroups []ec2.ISecurityGroup
+	AssignPublicIp bool
+}
+
+type loadBalancedFargateService struct {
+	constructs.Construct
+	logGroup       cloudwatchlogs.ILogGroup
+	fargateService ecs.FargateService
+	securityGroup  ec2.ISecurityGroup
+	fileSystem     efs.IFileSystem
+}
+
+type LoadBalancedFargateService interface {
+	// LogGroup is nil when Logging.ExistingLogGroupName imports the log group
+	LogGroup() cloudwatchlogs.LogGroup
+	Service() ecs.FargateService
+	SecurityGroup() ec2.ISecurityGroup
+	FileSystem() efs.IFileSystem
+}
+
+func (s *loadBalancedFargateService) Service() ecs.FargateService {
+	return s.fargateService
+}
+
+func (s *loadBalancedFargateService) LogGroup() cloudwatchlogs.LogGroup {
+	logGroup, _ := s.logGroup.(cloudwatchlogs.LogGroup)
+	return logGroup
+}
+
+func (s *loadBalancedFargateService) SecurityGroup() ec2.ISecurityGroup {
+	return s.securityGroup
+}
+
+func (s *loadBalancedFargateService) FileSystem() efs.IFileSystem {
+	return s.fileSystem
+}
+
+func NewLoadBalancedFargateService(scope constructs.Construct, id *string, props *LoadBalancedFargateServiceProps) LoadBalancedFargateService {
+	this := constructs.NewConstruct(scope, id)
+
+	cpu, memory := resolveFargateTaskSize(&props.TaskSize)
+
+	taskPolicyDocument := createTaskPolicyDocument(props.TaskDefinition.TaskPolicy, props.IsTracingEnabled)
+
+	taskDef := ecs.NewFargateTaskDefinition(this, jsii.String("FargateTaskDefinition"), &ecs.FargateTaskDefinitionProps{
+		Family:              jsii.String(props.TaskDefinition.FamilyName),
+		Cpu:                 jsii.Number(cpu),
+		MemoryLimitMiB:      jsii.Number(memory),
+		EphemeralStorageGiB: optionalNumber(props.TaskSize.EphemeralStorageGiB),
+		RuntimePlatform: &ecs.RuntimePlatform{
+			CpuArchitecture:       convertFargateCpuArchitecture(props.TaskSize.CpuArchitecture),
+			OperatingSystemFamily: ecs.OperatingSystemFamily_LINUX(),
+		},
+		ExecutionRole: createTaskExecutionRole(this, jsii.String("ExecutionRole")),
+		TaskRole:      createTaskRole(this, jsii.String("TaskRole"), taskPolicyDocument),
+	})
+
+	var ebsVolume *Volume = nil
+	if props.TaskDefinition.RequiresVolume {
+		ebsVolume = addTaskDefinitionVolumes(taskDef, props.TaskDefinition.Volumes, true)
+	}
+
+	// Creates a CloudWatch Log Group for each service
+	logGroup := createServiceLogGroup(this, jsii.String("LogGroup"), props.LogGroupName, &props.Logging)
+
+	addApplicationContainersToTaskDefinition(this, &props.TaskDefinition, taskDef, &props.Logging, logGroup, props.IsTracingEnabled)
+
+	cluster, vpc := resolveServiceCluster(this, props.Compute, &props.Cluster)
+
+	serviceSecurityGroup := ec2.NewSecurityGroup(this, jsii.String("ServiceSecurityGroup"), &ec2.SecurityGroupProps{
+		AllowAllOutbound: jsii.Bool(true),
+		Vpc:              vpc,
+		Description:      jsii.String("Security group for Fargate service tasks of " + props.TaskDefinition.FamilyName),
+	})
+	securityGroups := append([]ec2.ISecurityGroup{serviceSecurityGroup}, props.Network.SecurityGroups...)
+
+	fargateService := ecs.NewFargateService(this, jsii.String("FargateService"), &ecs.FargateServiceProps{
+		Cluster:                    cluster,
+		CapacityProviderStrategies: createFargateCapacityProviderStrategies(props.CapacityProviderStrategies),
+		TaskDefinition:             taskDef,
+		DesiredCount:               &props.DesiredTaskCount,
+		PlatformVersion:            resolveFargatePlatformVersion(props.PlatformVersion),
+		VpcSubnets:                 createFargateSubnetSelection(this, &props.Network),
+		SecurityGroups:             &securityGroups,
+		AssignPublicIp:             jsii.Bool(props.Network.AssignPublicIp),
+		CircuitBreaker: &ecs.DeploymentCircuitBreaker{
+			Rollback: jsii.Bool(true),
+		},
+		CloudMapOptions:      createServiceCloudMapOptions(this, props.IsServiceDiscoveryEnabled, &props.ServiceDiscovery, props.Compute),
+		PropagateTags:        ecs.PropagatedTagSource_SERVICE,
+		EnableECSManagedTags: jsii.Bool(true),
+	})
+
+	if ebsVolume != nil {
+		configureServiceEbsVolume(this, fargateService, ebsVolume)
+	}
+
+	var fileSystem efs.FileSystem = nil
+	if props.TaskDefinition.IsFileSystemEnabled {
+		fileSystem = createServiceFileSystem(this, jsii.String("FileSystem"), &props.TaskDefinition.FileSystem, vpc)
+		addFileSystemVolumes(fileSystem, &props.TaskDefinition.FileSystem, taskDef, true)
+		// tasks can only mount the file system once its mount targets exist
+		fargateService.Node().AddDependency(fileSystem.MountTargetsAvailable())
+		allowFileSystemAccess(fileSystem, []ec2.ISecurityGroup{serviceSecurityGroup})
+	}
+
+	var targetGroup elb2.ApplicationTargetGroup = nil
+	if props.IsLoadBalancerEnabled {
+		targetGroup, _ = attachServiceToLoadBalancer(
+			this,
+			vpc,
+			elb2.TargetType_IP,
+			fargateService.LoadBalancerTarget(&props.LoadBalancerTargetOptions),
+			resolveServiceListener(this, props.Compute, &props.LoadBalancer),
+			&props.LoadBalancer,
+			&props.LoadBalancerListener,
+			0,
+		)
+	}
+
+	attachServiceToNetworkLoadBalancers(fargateService, fargateService.Connections(), vpc, props.NetworkLoadBalancerTargets, false)
+
+	if props.IsAutoScalingEnabled {
+		configureServiceAutoScaling(fargateService, &props.AutoScaling, targetGroup)
+	}
+
+	return &loadBalancedFargateService{this, logGroup, fargateService, serviceSecurityGroup, fileSystem}
+}
+
+func resolveFargateTaskSize(props *FargateTaskSizeProps) (float64, float64) {
+	cpu := props.Cpu
+	if cpu == 0 {
+		cpu = DEFAULT_FARGATE_TASK_CPU
+	}
+	validMemory, ok := fargateTaskMemoryByCpu[cpu]
+	if !ok {
+		panic(fmt.Sprintf("invalid Fargate task cpu %v: must be one of 256, 512, 1024, 2048, 4096, 8192 or 16384", cpu))
+	}
+	memory := props.Memory
+	if memory == 0 {
+		memory = validMemory[0]
+	}
+	for _, m := range validMemory {
+		if m == memory {
+			return cpu, memory
+		}
+	}
+	panic(fmt.Sprintf("invalid Fargate task memory %v MiB for cpu %v: must be one of %v", memory, cpu, validMemory))
+}
+
+func rangeOfMemory(min float64, max float64, step float64) []float64 {
+	memory := []float64{}
+	for m := min; m <= max; m += step {
+		memory = append(memory, m)
+	}
+	return memory
+}
+
+func convertFargateCpuArchitecture(arch FargateCpuArchitecture) ecs.CpuArchitecture {
+	if arch == FARGATE_CPU_ARCHITECTURE_ARM64 {
+		return ecs.CpuArchitecture_ARM64()
+	}
+	return ecs.CpuArchitecture_X86_64()
+}
+
+func resolveFargatePlatformVersion(version ecs.FargatePlatformVersion) ecs.FargatePlatformVersion {
+	if version == "" {
+		return DEFAULT_FARGATE_PLATFORM_VERSION
+	}
+	return version
+}
+
+func createFargateCapacityProviderStrategies(strategies []FargateCapacityProviderStrategy) *[]*ecs.CapacityProviderStrategy {
+	if len(strategies) == 0 {
+		strategies = []FargateCapacityProviderStrategy{
+			{CapacityProvider: DEFAULT_FARGATE_CAPACITY_PROVIDER, Weight: 1},
+		}
+	}
+
+	isWeighted := false
+	for _, strategy := range strategies {
+		isWeighted = isWeighted || strategy.Weight > 0
+	}
+
+	capacityProviderStrategies := []*ecs.CapacityProviderStrategy{}
+	for _, strategy := range strategies {
+		if strategy.CapacityProvider != FARGATE_CAPACITY_PROVIDER_FARGATE && strategy.CapacityProvider != FARGATE_CAPACITY_PROVIDER_FARGATE_SPOT {
+			panic(fmt.Sprintf("invalid Fargate capacity provider %q: must be FARGATE or FARGATE_SPOT", strategy.CapacityProvider))
+		}
+		// ECS places no tasks when every weight is zero, a zero weight is only meant for base-only strategies
+		weight := strategy.Weight
+		if !isWeighted {
+			weight = 1
+		}
+		capacityProviderStrategies = append(capacityProviderStrategies, &ecs.CapacityProviderStrategy{
+			CapacityProvider: jsii.String(string(strategy.CapacityProvider)),
+			Weight:           jsii.Number(weight),
+			Base:             optionalNumber(strategy.Base),
+		})
+	}
+	return &capacityProviderStrategies
+}
+
+func createFargateSubnetSelection(scope constructs.Construct, props *FargateNetworkProps) *ec2.SubnetSelection {
+	if len(props.SubnetIds) > 0 {
+		subnets := []ec2.ISubnet{}
+		for _, subnetId := range props.SubnetIds {
+			subnets = append(subnets, ec2.Subnet_FromSubnetId(scope, jsii.String("Subnet"+subnetId), jsii.String(subnetId)))
+		}
+		return &ec2.SubnetSelection{Subnets: &subnets}
+	}
+
+	subnetType := props.SubnetType
+	if subnetType == "" {
+		subnetType = DEFAULT_FARGATE_SUBNET_TYPE
+	}
+	return &ec2.SubnetSelection{SubnetType: subnetType}
+}
+
+func optionalNumber(n float64) *float64 {
+	if n == 0 {
+		return nil
+	}
+	return jsii.Number(n)
+}
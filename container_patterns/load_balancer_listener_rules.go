@@ -0,0 +1,185 @@
+package containerpatterns
+
+import (
+	"fmt"
+
+	elb2 "github.com/aws/aws-cdk-go/awscdk/v2/awselasticloadbalancingv2"
+	"github.com/aws/constructs-go/constructs/v10"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// custom types
+type ListenerActionType string
+
+const (
+	LISTENER_ACTION_TYPE_FORWARD          ListenerActionType = "FORWARD"
+	LISTENER_ACTION_TYPE_WEIGHTED_FORWARD ListenerActionType = "WEIGHTED_FORWARD"
+	LISTENER_ACTION_TYPE_FIXED_RESPONSE   ListenerActionType = "FIXED_RESPONSE"
+	LISTENER_ACTION_TYPE_REDIRECT         ListenerActionType = "REDIRECT"
+	DEFAULT_LISTENER_ACTION_TYPE          ListenerActionType = LISTENER_ACTION_TYPE_FORWARD
+)
+
+// ListenerRuleConditionProps lists the conditions a request has to meet, values within one condition are alternatives.
+type ListenerRuleConditionProps struct {
+	HostHeaders  []string
+	PathPatterns []string
+	HttpHeaders  []HttpHeaderConditionProps
+	QueryStrings []QueryStringConditionProps
+	HttpMethods  []string
+	SourceIps    []string
+}
+
+type HttpHeaderConditionProps struct {
+	Name   string
+	Values []string
+}
+
+// QueryStringConditionProps matches Value against the query parameter Key, or against any parameter value without Key
+type QueryStringConditionProps struct {
+	Key   string
+	Value string
+}
+
+// ListenerRuleProps is an additional rule on the service's listener, FORWARD sends matching requests to the service.
+type ListenerRuleProps struct {
+	Priority   float64
+	Conditions ListenerRuleConditionProps
+	Action     ListenerRuleActionProps
+}
+
+type ListenerRuleActionProps struct {
+	Type                 ListenerActionType
+	WeightedTargetGroups []WeightedTargetGroupProps
+	FixedResponse        FixedResponseProps
+	Redirect             RedirectProps
+}
+
+// WeightedTargetGroupProps without a TargetGroupArn weights the service's own target group
+type WeightedTargetGroupProps struct {
+	TargetGroupArn string
+	Weight         float64
+}
+
+type FixedResponseProps struct {
+	StatusCode  float64
+	ContentType string
+	MessageBody string
+}
+
+// RedirectProps keeps the request's own value for any part left empty
+type RedirectProps struct {
+	Protocol    string
+	Host        string
+	Port        string
+	Path        string
+	Query       string
+	IsPermanent bool
+}
+
+// resolveServiceListenerConditions merges the single host and path conditions into the service rule's conditions
+func resolveServiceListenerConditions(listenerProps *LoadBalancerListenerProps) *ListenerRuleConditionProps {
+	conditions := listenerProps.Conditions
+	if listenerProps.HostCondition != "" {
+		conditions.HostHeaders = append([]string{listenerProps.HostCondition}, conditions.HostHeaders...)
+	}
+	if listenerProps.PathCondition != "" {
+		conditions.PathPatterns = append([]string{listenerProps.PathCondition}, conditions.PathPatterns...)
+	}
+	return &conditions
+}
+
+func createServiceListenerRule(scope constructs.Construct, id *string, listener elb2.IApplicationListener, priority float64, allocation RulePriorityAllocation, conditions *ListenerRuleConditionProps, action elb2.ListenerAction) elb2.ApplicationListenerRule {
+	rule := createPrioritizedListenerRule(scope, id, listener, priority, allocation, &elb2.ApplicationListenerRuleProps{
+		Action:     action,
+		Conditions: createListenerConditions(*id, conditions),
+		Listener:   listener,
+	})
+	return rule
+}
+
+func createListenerConditions(ruleId string, props *ListenerRuleConditionProps) *[]elb2.ListenerCondition {
+	conditions := []elb2.ListenerCondition{}
+	if len(props.HostHeaders) > 0 {
+		conditions = append(conditions, elb2.ListenerCondition_HostHeaders(jsii.Strings(props.HostHeaders...)))
+	}
+	if len(props.PathPatterns) > 0 {
+		conditions = append(conditions, elb2.ListenerCondition_PathPatterns(jsii.Strings(props.PathPatterns...)))
+	}
+	for _, header := range props.HttpHeaders {
+		conditions = append(conditions, elb2.ListenerCondition_HttpHeader(jsii.String(header.Name), jsii.Strings(header.Values...)))
+	}
+	if len(props.QueryStrings) > 0 {
+		queryStrings := []*elb2.QueryStringCondition{}
+		for _, queryString := range props.QueryStrings {
+			queryStrings = append(queryStrings, &elb2.QueryStringCondition{
+				Key:   optionalString(queryString.Key),
+				Value: jsii.String(queryString.Value),
+			})
+		}
+		conditions = append(conditions, elb2.ListenerCondition_QueryStrings(&queryStrings))
+	}
+	if len(props.HttpMethods) > 0 {
+		conditions = append(conditions, elb2.ListenerCondition_HttpRequestMethods(jsii.Strings(props.HttpMethods...)))
+	}
+	if len(props.SourceIps) > 0 {
+		conditions = append(conditions, elb2.ListenerCondition_SourceIps(jsii.Strings(props.SourceIps...)))
+	}
+
+	if len(conditions) == 0 {
+		panic("listener rule " + ruleId + " requires at least one condition")
+	}
+	return &conditions
+}
+
+// addServiceListenerRules creates the additional rules of the service, each in front of the same listener
+func addServiceListenerRules(scope constructs.Construct, listener elb2.IApplicationListener, rules []ListenerRuleProps, allocation RulePriorityAllocation, targetGroup elb2.IApplicationTargetGroup) {
+	for index := range rules {
+		rule := &rules[index]
+		id := fmt.Sprintf("ALBListenerRule%d", index)
+		createServiceListenerRule(scope, jsii.String(id), listener, rule.Priority, allocation, &rule.Conditions, createListenerAction(scope, id, &rule.Action, targetGroup))
+	}
+}
+
+func createListenerAction(scope constructs.Construct, ruleId string, props *ListenerRuleActionProps, targetGroup elb2.IApplicationTargetGroup) elb2.ListenerAction {
+	switch props.Type {
+	case "", LISTENER_ACTION_TYPE_FORWARD:
+		return elb2.ListenerAction_Forward(&[]elb2.IApplicationTargetGroup{targetGroup}, &elb2.ForwardOptions{})
+	case LISTENER_ACTION_TYPE_WEIGHTED_FORWARD:
+		if len(props.WeightedTargetGroups) == 0 {
+			panic("weighted forward action of listener rule " + ruleId + " requires WeightedTargetGroups")
+		}
+		weightedTargetGroups := []*elb2.WeightedTargetGroup{}
+		for index, weighted := range props.WeightedTargetGroups {
+			var weightedTargetGroup elb2.IApplicationTargetGroup = targetGroup
+			if weighted.TargetGroupArn != "" {
+				weightedTargetGroup = elb2.ApplicationTargetGroup_FromTargetGroupAttributes(scope, jsii.String(fmt.Sprintf("%sTargetGroup%d", ruleId, index)), &elb2.TargetGroupAttributes{
+					TargetGroupArn: jsii.String(weighted.TargetGroupArn),
+				})
+			}
+			weightedTargetGroups = append(weightedTargetGroups, &elb2.WeightedTargetGroup{
+				TargetGroup: weightedTargetGroup,
+				Weight:      jsii.Number(weighted.Weight),
+			})
+		}
+		return elb2.ListenerAction_WeightedForward(&weightedTargetGroups, &elb2.ForwardOptions{})
+	case LISTENER_ACTION_TYPE_FIXED_RESPONSE:
+		if props.FixedResponse.StatusCode == 0 {
+			panic("fixed response action of listener rule " + ruleId + " requires StatusCode")
+		}
+		return elb2.ListenerAction_FixedResponse(jsii.Number(props.FixedResponse.StatusCode), &elb2.FixedResponseOptions{
+			ContentType: optionalString(props.FixedResponse.ContentType),
+			MessageBody: optionalString(props.FixedResponse.MessageBody),
+		})
+	case LISTENER_ACTION_TYPE_REDIRECT:
+		return elb2.ListenerAction_Redirect(&elb2.RedirectOptions{
+			Protocol:  optionalString(props.Redirect.Protocol),
+			Host:      optionalString(props.Redirect.Host),
+			Port:      optionalString(props.Redirect.Port),
+			Path:      optionalString(props.Redirect.Path),
+			Query:     optionalString(props.Redirect.Query),
+			Permanent: jsii.Bool(props.Redirect.IsPermanent),
+		})
+	default:
+		panic(fmt.Sprintf("invalid action type %q for listener rule %s", props.Type, ruleId))
+	}
+}
@@ -0,0 +1,147 @@
+package containerpatterns
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	ec2 "github.com/aws/aws-cdk-go/awscdk/v2/awsec2"
+	elb2 "github.com/aws/aws-cdk-go/awscdk/v2/awselasticloadbalancingv2"
+	"github.com/aws/constructs-go/constructs/v10"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// custom types
+type StickinessType string
+
+const (
+	STICKINESS_TYPE_NONE       StickinessType = "NONE"
+	STICKINESS_TYPE_LB_COOKIE  StickinessType = "LB_COOKIE"
+	STICKINESS_TYPE_APP_COOKIE StickinessType = "APP_COOKIE"
+	DEFAULT_STICKINESS_TYPE    StickinessType = STICKINESS_TYPE_NONE
+)
+
+const (
+	DEFAULT_TARGET_PROTOCOL                  elb2.ApplicationProtocol = elb2.ApplicationProtocol_HTTP
+	DEFAULT_HEALTH_CHECK_HTTP_CODES          string                   = "200"
+	DEFAULT_HEALTH_CHECK_GRPC_CODES          string                   = "12"
+	DEFAULT_HEALTH_CHECK_GRPC_PATH           string                   = "/AWS.ALB/healthcheck"
+	DEFAULT_HEALTH_CHECK_INTERVAL_SECONDS    float64                  = 30
+	DEFAULT_STICKINESS_COOKIE_DURATION_HOURS float64                  = 24
+)
+
+// TargetGroupHealthCheckProps configures the target group health check, zero values keep the load balancer defaults.
+// SuccessCodes are HTTP status codes, or gRPC status codes for gRPC targets, given as a value, list or range such as
+// "200-299". Path takes precedence over LoadBalancerProps.LoadBalancerHealthCheckPath.
+type TargetGroupHealthCheckProps struct {
+	Path                    string
+	Port                    string
+	Protocol                elb2.Protocol
+	SuccessCodes            string
+	IntervalSeconds         float64
+	TimeoutSeconds          float64
+	HealthyThresholdCount   float64
+	UnhealthyThresholdCount float64
+}
+
+// TargetGroupStickinessProps binds a client to a target with a load balancer generated cookie, or with the
+// application's own cookie named CookieName.
+type TargetGroupStickinessProps struct {
+	Type            StickinessType
+	DurationSeconds float64
+	CookieName      string
+}
+
+func createServiceTargetGroup(scope constructs.Construct, id *string, vpc ec2.IVpc, targetType elb2.TargetType, lbProps *LoadBalancerProps, deregistrationDelaySeconds float64) elb2.ApplicationTargetGroup {
+	protocol := lbProps.TargetProtocol
+	if protocol == "" {
+		protocol = DEFAULT_TARGET_PROTOCOL
+	}
+	if lbProps.SlowStartSeconds != 0 {
+		if lbProps.SlowStartSeconds < 30 || lbProps.SlowStartSeconds > 900 {
+			panic(fmt.Sprintf("invalid target group slow start of %v seconds, must be between 30 and 900", lbProps.SlowStartSeconds))
+		}
+		if lbProps.LoadBalancingAlgorithm == elb2.TargetGroupLoadBalancingAlgorithmType_LEAST_OUTSTANDING_REQUESTS {
+			panic("target group slow start cannot be combined with the least outstanding requests algorithm")
+		}
+	}
+
+	stickinessCookieDuration, stickinessCookieName := createTargetGroupStickiness(&lbProps.Stickiness)
+
+	targetGroup := elb2.NewApplicationTargetGroup(scope, id, &elb2.ApplicationTargetGroupProps{
+		HealthCheck:                createTargetGroupHealthCheck(lbProps),
+		TargetType:                 targetType,
+		Vpc:                        vpc,
+		Protocol:                   protocol,
+		ProtocolVersion:            lbProps.TargetProtocolVersion,
+		DeregistrationDelay:        optionalDurationSeconds(deregistrationDelaySeconds),
+		SlowStart:                  optionalDurationSeconds(lbProps.SlowStartSeconds),
+		StickinessCookieDuration:   stickinessCookieDuration,
+		StickinessCookieName:       stickinessCookieName,
+		LoadBalancingAlgorithmType: lbProps.LoadBalancingAlgorithm,
+	})
+	return targetGroup
+}
+
+// createTargetGroupHealthCheck matches gRPC targets on gRPC status codes against the default gRPC health check path
+func createTargetGroupHealthCheck(lbProps *LoadBalancerProps) *elb2.HealthCheck {
+	props := &lbProps.HealthCheck
+	isGrpc := lbProps.TargetProtocolVersion == elb2.ApplicationProtocolVersion_GRPC
+
+	path := props.Path
+	if path == "" {
+		path = lbProps.LoadBalancerHealthCheckPath
+	}
+	if path == "" && isGrpc {
+		path = DEFAULT_HEALTH_CHECK_GRPC_PATH
+	}
+	interval := props.IntervalSeconds
+	if interval == 0 {
+		interval = DEFAULT_HEALTH_CHECK_INTERVAL_SECONDS
+	}
+
+	healthCheck := &elb2.HealthCheck{
+		Enabled:                 jsii.Bool(true),
+		Path:                    jsii.String(path),
+		Port:                    optionalString(props.Port),
+		Protocol:                props.Protocol,
+		Interval:                awscdk.Duration_Seconds(jsii.Number(interval)),
+		Timeout:                 optionalDurationSeconds(props.TimeoutSeconds),
+		HealthyThresholdCount:   optionalNumber(props.HealthyThresholdCount),
+		UnhealthyThresholdCount: optionalNumber(props.UnhealthyThresholdCount),
+	}
+	if isGrpc {
+		healthCheck.HealthyGrpcCodes = jsii.String(DEFAULT_HEALTH_CHECK_GRPC_CODES)
+		if props.SuccessCodes != "" {
+			healthCheck.HealthyGrpcCodes = jsii.String(props.SuccessCodes)
+		}
+	} else {
+		healthCheck.HealthyHttpCodes = jsii.String(DEFAULT_HEALTH_CHECK_HTTP_CODES)
+		if props.SuccessCodes != "" {
+			healthCheck.HealthyHttpCodes = jsii.String(props.SuccessCodes)
+		}
+	}
+	return healthCheck
+}
+
+// createTargetGroupStickiness returns the cookie duration and name, a cookie name switches the target group to
+// application cookie stickiness.
+func createTargetGroupStickiness(props *TargetGroupStickinessProps) (awscdk.Duration, *string) {
+	duration := awscdk.Duration_Hours(jsii.Number(DEFAULT_STICKINESS_COOKIE_DURATION_HOURS))
+	if props.DurationSeconds > 0 {
+		duration = awscdk.Duration_Seconds(jsii.Number(props.DurationSeconds))
+	}
+
+	switch props.Type {
+	case "", STICKINESS_TYPE_NONE:
+		return nil, nil
+	case STICKINESS_TYPE_LB_COOKIE:
+		return duration, nil
+	case STICKINESS_TYPE_APP_COOKIE:
+		if props.CookieName == "" {
+			panic("application cookie stickiness requires CookieName")
+		}
+		return duration, jsii.String(props.CookieName)
+	default:
+		panic(fmt.Sprintf("invalid stickiness type %q", props.Type))
+	}
+}
@@ -0,0 +1,181 @@
+package containerpatterns
+
+import (
+	"fmt"
+
+	ec2 "github.com/aws/aws-cdk-go/awscdk/v2/awsec2"
+	ecs "github.com/aws/aws-cdk-go/awscdk/v2/awsecs"
+	elbv2 "github.com/aws/aws-cdk-go/awscdk/v2/awselasticloadbalancingv2"
+	"github.com/aws/constructs-go/constructs/v10"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// constants
+const (
+	DEFAULT_NETWORK_LISTENER_PROTOCOL elbv2.Protocol   = elbv2.Protocol_TCP
+	DEFAULT_NETWORK_TARGET_TYPE       elbv2.TargetType = elbv2.TargetType_IP
+)
+
+// bridge mode containers are published on a host port ECS picks from the ephemeral range
+const (
+	EPHEMERAL_HOST_PORT_MIN float64 = 32768
+	EPHEMERAL_HOST_PORT_MAX float64 = 65535
+)
+
+// ContainerComputeNetworkLoadBalancerProps configures an NLB for TCP, UDP and TLS services next to the shared ALB
+type ContainerComputeNetworkLoadBalancerProps struct {
+	Name                        string
+	Scheme                      LoadBalancerScheme
+	SubnetType                  ec2.SubnetType
+	SubnetIds                   []string
+	IpAddressType               elbv2.IpAddressType
+	IsDeletionProtectionEnabled bool
+	IsCrossZoneEnabled          bool
+	Listeners                   []NetworkListenerProps
+}
+
+// NetworkListenerProps forwards Port to a target group the service registers with, see
+// ContainerCompute.NetworkTargetGroups. TLS listeners terminate TLS with CertificateArn and forward TCP unless
+// TargetProtocol says otherwise. TargetPort defaults to Port, TargetType to IP targets as used in awsvpc mode.
+type NetworkListenerProps struct {
+	Name           string
+	Port           float64
+	Protocol       elbv2.Protocol
+	CertificateArn string
+	TargetPort     float64
+	TargetProtocol elbv2.Protocol
+	TargetType     elbv2.TargetType
+}
+
+// NetworkLoadBalancerTargetProps registers a container port with a target group of the NLB, usually one of
+// ContainerCompute.NetworkTargetGroups. The NLB has no security group and passes the client address on, so the tasks
+// accept the port from the VPC, which covers health checks and internal clients, and from IngressPeers, e.g.
+// ec2.Peer_AnyIpv4() behind an internet-facing NLB. Bridge mode services need a target group of INSTANCE targets.
+type NetworkLoadBalancerTargetProps struct {
+	TargetGroup   elbv2.INetworkTargetGroup
+	ContainerName string
+	ContainerPort float64
+	Protocol      ecs.Protocol
+	IngressPeers  []ec2.IPeer
+}
+
+func createNetworkLoadBalancer(scope constructs.Construct, id *string, props *ContainerComputeNetworkLoadBalancerProps, vpc ec2.IVpc) elbv2.NetworkLoadBalancer {
+	isInternetFacing := isLoadBalancerInternetFacing(props.Scheme)
+
+	nlb := elbv2.NewNetworkLoadBalancer(scope, id, &elbv2.NetworkLoadBalancerProps{
+		LoadBalancerName:   jsii.String(props.Name),
+		Vpc:                vpc,
+		InternetFacing:     jsii.Bool(isInternetFacing),
+		VpcSubnets:         createLoadBalancerSubnetSelection(scope, props.Name, isInternetFacing, props.SubnetType, props.SubnetIds),
+		DeletionProtection: jsii.Bool(props.IsDeletionProtectionEnabled),
+		CrossZoneEnabled:   jsii.Bool(props.IsCrossZoneEnabled),
+	})
+	// the pinned CDK version only sets the IP address type on application load balancers
+	switch props.IpAddressType {
+	case "", elbv2.IpAddressType_IPV4:
+	case elbv2.IpAddressType_DUAL_STACK:
+		nlb.Node().DefaultChild().(elbv2.CfnLoadBalancer).SetIpAddressType(jsii.String("dualstack"))
+	default:
+		panic(fmt.Sprintf("invalid load balancer IP address type %q", props.IpAddressType))
+	}
+	return nlb
+}
+
+// addNetworkListeners creates each listener with the target group it forwards to, keyed by listener name
+func addNetworkListeners(scope constructs.Construct, nlb elbv2.NetworkLoadBalancer, props *ContainerComputeNetworkLoadBalancerProps, vpc ec2.IVpc) map[string]elbv2.INetworkTargetGroup {
+	targetGroups := map[string]elbv2.INetworkTargetGroup{}
+	for _, listenerProps := range props.Listeners {
+		if listenerProps.Name == "" || listenerProps.Port == 0 {
+			panic("network listener requires Name and Port")
+		}
+		if _, exists := targetGroups[listenerProps.Name]; exists {
+			panic("duplicate network listener name " + listenerProps.Name)
+		}
+
+		protocol := listenerProps.Protocol
+		if protocol == "" {
+			protocol = DEFAULT_NETWORK_LISTENER_PROTOCOL
+		}
+		targetProtocol := listenerProps.TargetProtocol
+		if targetProtocol == "" {
+			targetProtocol = protocol
+			if protocol == elbv2.Protocol_TLS {
+				targetProtocol = elbv2.Protocol_TCP
+			}
+		}
+		targetPort := listenerProps.TargetPort
+		if targetPort == 0 {
+			targetPort = listenerProps.Port
+		}
+		targetType := listenerProps.TargetType
+		if targetType == "" {
+			targetType = DEFAULT_NETWORK_TARGET_TYPE
+		}
+
+		var certificates *[]elbv2.IListenerCertificate = nil
+		switch protocol {
+		case elbv2.Protocol_TLS:
+			if listenerProps.CertificateArn == "" {
+				panic("TLS network listener " + listenerProps.Name + " requires CertificateArn")
+			}
+			certificates = &[]elbv2.IListenerCertificate{elbv2.ListenerCertificate_FromArn(jsii.String(listenerProps.CertificateArn))}
+		case elbv2.Protocol_TCP, elbv2.Protocol_UDP, elbv2.Protocol_TCP_UDP:
+		default:
+			panic(fmt.Sprintf("invalid network listener protocol %q, must be TCP, UDP, TCP_UDP or TLS", protocol))
+		}
+
+		targetGroup := elbv2.NewNetworkTargetGroup(scope, jsii.String(listenerProps.Name+"NetworkTargetGroup"), &elbv2.NetworkTargetGroupProps{
+			Port:       jsii.Number(targetPort),
+			Protocol:   targetProtocol,
+			TargetType: targetType,
+			Vpc:        vpc,
+		})
+		nlb.AddListener(jsii.String(listenerProps.Name+"NetworkListener"), &elbv2.BaseNetworkListenerProps{
+			Port:                jsii.Number(listenerProps.Port),
+			Protocol:            protocol,
+			Certificates:        certificates,
+			DefaultTargetGroups: &[]elbv2.INetworkTargetGroup{targetGroup},
+		})
+		targetGroups[listenerProps.Name] = targetGroup
+	}
+	return targetGroups
+}
+
+// attachServiceToNetworkLoadBalancers registers the service with each target group and opens the tasks' port to the
+// clients, connections are the security groups the tasks receive traffic on.
+func attachServiceToNetworkLoadBalancers(service ecs.BaseService, connections ec2.Connections, vpc ec2.IVpc, targets []NetworkLoadBalancerTargetProps, isBridgeMode bool) {
+	for _, targetProps := range targets {
+		if targetProps.TargetGroup == nil || targetProps.ContainerName == "" || targetProps.ContainerPort == 0 {
+			panic("network load balancer target requires TargetGroup, ContainerName and ContainerPort")
+		}
+		protocol := targetProps.Protocol
+		if protocol == "" {
+			protocol = ecs.Protocol_TCP
+		}
+
+		targetProps.TargetGroup.AddTarget(service.LoadBalancerTarget(&ecs.LoadBalancerTargetOptions{
+			ContainerName: jsii.String(targetProps.ContainerName),
+			ContainerPort: jsii.Number(targetProps.ContainerPort),
+			Protocol:      protocol,
+		}))
+
+		fromPort, toPort := targetProps.ContainerPort, targetProps.ContainerPort
+		if isBridgeMode {
+			fromPort, toPort = EPHEMERAL_HOST_PORT_MIN, EPHEMERAL_HOST_PORT_MAX
+		}
+		var port ec2.Port
+		switch protocol {
+		case ecs.Protocol_TCP:
+			port = ec2.Port_TcpRange(jsii.Number(fromPort), jsii.Number(toPort))
+		case ecs.Protocol_UDP:
+			port = ec2.Port_UdpRange(jsii.Number(fromPort), jsii.Number(toPort))
+		default:
+			panic(fmt.Sprintf("invalid network load balancer target protocol %q, must be tcp or udp", protocol))
+		}
+
+		peers := append([]ec2.IPeer{ec2.Peer_Ipv4(vpc.VpcCidrBlock())}, targetProps.IngressPeers...)
+		for _, peer := range peers {
+			connections.AllowFrom(peer, port, jsii.String(fmt.Sprintf("Network load balancer traffic to %s:%v", targetProps.ContainerName, targetProps.ContainerPort)))
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package containerpatterns
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	appscaling "github.com/aws/aws-cdk-go/awscdk/v2/awsapplicationautoscaling"
+	cloudwatch "github.com/aws/aws-cdk-go/awscdk/v2/awscloudwatch"
+	ecs "github.com/aws/aws-cdk-go/awscdk/v2/awsecs"
+	elb2 "github.com/aws/aws-cdk-go/awscdk/v2/awselasticloadbalancingv2"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// custom types
+type ScalingMetric string
+
+const (
+	SCALING_METRIC_CPU_UTILIZATION    ScalingMetric = "CPU_UTILIZATION"
+	SCALING_METRIC_MEMORY_UTILIZATION ScalingMetric = "MEMORY_UTILIZATION"
+	SCALING_METRIC_CUSTOM             ScalingMetric = "CUSTOM"
+)
+
+type ServiceAutoScalingProps struct {
+	MinTaskCount          float64
+	MaxTaskCount          float64
+	CpuUtilization        TargetTrackingScalingProps
+	MemoryUtilization     TargetTrackingScalingProps
+	RequestCountPerTarget TargetTrackingScalingProps
+	StepScaling           []StepScalingProps
+	ScheduledActions      []ScheduledScalingProps
+}
+
+// TargetTrackingScalingProps configures a target tracking policy, a zero TargetValue leaves the policy out
+type TargetTrackingScalingProps struct {
+	TargetValue             float64
+	ScaleInCooldownSeconds  float64
+	ScaleOutCooldownSeconds float64
+	DisableScaleIn          bool
+}
+
+type StepScalingProps struct {
+	Name              string
+	Metric            ScalingMetric
+	CustomMetric      cloudwatch.IMetric
+	ScalingSteps      []appscaling.ScalingInterval
+	AdjustmentType    appscaling.AdjustmentType
+	CooldownSeconds   float64
+	EvaluationPeriods float64
+}
+
+// ScheduledScalingProps sets the task counts at the given schedule, a nil count leaves that bound unchanged. Zero is a
+// valid count, e.g. MinTaskCount and MaxTaskCount of jsii.Number(0) stop all tasks overnight.
+type ScheduledScalingProps struct {
+	Name         string
+	Schedule     string
+	MinTaskCount *float64
+	MaxTaskCount *float64
+}
+
+func configureServiceAutoScaling(service ecs.BaseService, props *ServiceAutoScalingProps, targetGroup elb2.ApplicationTargetGroup) ecs.ScalableTaskCount {
+	if props.MaxTaskCount <= 0 || props.MinTaskCount > props.MaxTaskCount {
+		panic(fmt.Sprintf("invalid service auto scaling task counts: min %v, max %v", props.MinTaskCount, props.MaxTaskCount))
+	}
+
+	scalableTaskCount := service.AutoScaleTaskCount(&appscaling.EnableScalingProps{
+		MinCapacity: jsii.Number(props.MinTaskCount),
+		MaxCapacity: jsii.Number(props.MaxTaskCount),
+	})
+
+	if props.CpuUtilization.TargetValue > 0 {
+		scalableTaskCount.ScaleOnCpuUtilization(jsii.String("CpuUtilizationScaling"), &ecs.CpuUtilizationScalingProps{
+			TargetUtilizationPercent: jsii.Number(props.CpuUtilization.TargetValue),
+			ScaleInCooldown:          optionalDurationSeconds(props.CpuUtilization.ScaleInCooldownSeconds),
+			ScaleOutCooldown:         optionalDurationSeconds(props.CpuUtilization.ScaleOutCooldownSeconds),
+			DisableScaleIn:           jsii.Bool(props.CpuUtilization.DisableScaleIn),
+		})
+	}
+
+	if props.MemoryUtilization.TargetValue > 0 {
+		scalableTaskCount.ScaleOnMemoryUtilization(jsii.String("MemoryUtilizationScaling"), &ecs.MemoryUtilizationScalingProps{
+			TargetUtilizationPercent: jsii.Number(props.MemoryUtilization.TargetValue),
+			ScaleInCooldown:          optionalDurationSeconds(props.MemoryUtilization.ScaleInCooldownSeconds),
+			ScaleOutCooldown:         optionalDurationSeconds(props.MemoryUtilization.ScaleOutCooldownSeconds),
+			DisableScaleIn:           jsii.Bool(props.MemoryUtilization.DisableScaleIn),
+		})
+	}
+
+	if props.RequestCountPerTarget.TargetValue > 0 {
+		if targetGroup == nil {
+			panic("request count auto scaling requires the service to be attached to a load balancer")
+		}
+		scalableTaskCount.ScaleOnRequestCount(jsii.String("RequestCountScaling"), &ecs.RequestCountScalingProps{
+			RequestsPerTarget: jsii.Number(props.RequestCountPerTarget.TargetValue),
+			TargetGroup:       targetGroup,
+			ScaleInCooldown:   optionalDurationSeconds(props.RequestCountPerTarget.ScaleInCooldownSeconds),
+			ScaleOutCooldown:  optionalDurationSeconds(props.RequestCountPerTarget.ScaleOutCooldownSeconds),
+			DisableScaleIn:    jsii.Bool(props.RequestCountPerTarget.DisableScaleIn),
+		})
+	}
+
+	stepScalingNames := map[string]bool{}
+	for _, stepScaling := range props.StepScaling {
+		validateScalingName("step scaling policy", stepScaling.Name, stepScalingNames)
+		scalingSteps := []*appscaling.ScalingInterval{}
+		for index := range stepScaling.ScalingSteps {
+			scalingSteps = append(scalingSteps, &stepScaling.ScalingSteps[index])
+		}
+		scalableTaskCount.ScaleOnMetric(jsii.String(stepScaling.Name+"StepScaling"), &appscaling.BasicStepScalingPolicyProps{
+			Metric:            resolveScalingMetric(service, &stepScaling),
+			ScalingSteps:      &scalingSteps,
+			AdjustmentType:    stepScaling.AdjustmentType,
+			Cooldown:          optionalDurationSeconds(stepScaling.CooldownSeconds),
+			EvaluationPeriods: optionalNumber(stepScaling.EvaluationPeriods),
+		})
+	}
+
+	scheduledActionNames := map[string]bool{}
+	for _, scheduledAction := range props.ScheduledActions {
+		validateScalingName("scheduled scaling", scheduledAction.Name, scheduledActionNames)
+		minTaskCount, maxTaskCount := scheduledAction.MinTaskCount, scheduledAction.MaxTaskCount
+		if minTaskCount == nil && maxTaskCount == nil {
+			panic("scheduled scaling " + scheduledAction.Name + " requires MinTaskCount or MaxTaskCount")
+		}
+		if (minTaskCount != nil && *minTaskCount < 0) || (maxTaskCount != nil && *maxTaskCount < 0) ||
+			(minTaskCount != nil && maxTaskCount != nil && *minTaskCount > *maxTaskCount) {
+			panic(fmt.Sprintf("invalid task counts for scheduled scaling %s: min %v, max %v", scheduledAction.Name, formatOptionalNumber(minTaskCount), formatOptionalNumber(maxTaskCount)))
+		}
+		scalableTaskCount.ScaleOnSchedule(jsii.String(scheduledAction.Name+"ScheduledScaling"), &appscaling.ScalingSchedule{
+			Schedule:    appscaling.Schedule_Expression(jsii.String(scheduledAction.Schedule)),
+			MinCapacity: minTaskCount,
+			MaxCapacity: maxTaskCount,
+		})
+	}
+
+	return scalableTaskCount
+}
+
+// validateScalingName rejects empty and repeated names, they become part of the policy's construct ID
+func validateScalingName(kind string, name string, names map[string]bool) {
+	if name == "" {
+		panic(kind + " requires a Name")
+	}
+	if names[name] {
+		panic("duplicate " + kind + " name " + name)
+	}
+	names[name] = true
+}
+
+func formatOptionalNumber(n *float64) string {
+	if n == nil {
+		return "unset"
+	}
+	return fmt.Sprint(*n)
+}
+
+func resolveScalingMetric(service ecs.BaseService, props *StepScalingProps) cloudwatch.IMetric {
+	switch props.Metric {
+	case SCALING_METRIC_CPU_UTILIZATION:
+		return service.MetricCpuUtilization(&cloudwatch.MetricOptions{})
+	case SCALING_METRIC_MEMORY_UTILIZATION:
+		return service.MetricMemoryUtilization(&cloudwatch.MetricOptions{})
+	case SCALING_METRIC_CUSTOM:
+		if props.CustomMetric == nil {
+			panic("step scaling policy " + props.Name + " uses a custom metric but CustomMetric is not set")
+		}
+		return props.CustomMetric
+	default:
+		panic(fmt.Sprintf("invalid step scaling metric %q for policy %s", props.Metric, props.Name))
+	}
+}
+
+func optionalDurationSeconds(seconds float64) awscdk.Duration {
+	if seconds == 0 {
+		return nil
+	}
+	return awscdk.Duration_Seconds(jsii.Number(seconds))
+}
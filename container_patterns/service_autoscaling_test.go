@@ -0,0 +1,115 @@
+package containerpatterns
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2/assertions"
+	appscaling "github.com/aws/aws-cdk-go/awscdk/v2/awsapplicationautoscaling"
+	"github.com/aws/jsii-runtime-go"
+)
+
+func TestScheduledScalingCanStopAllTasks(t *testing.T) {
+	stack := newTestStack()
+	service := newTestFargateService(stack)
+	configureServiceAutoScaling(service, &ServiceAutoScalingProps{
+		MinTaskCount: 1,
+		MaxTaskCount: 4,
+		ScheduledActions: []ScheduledScalingProps{
+			{Name: "Night", Schedule: "cron(0 22 * * ? *)", MinTaskCount: jsii.Number(0), MaxTaskCount: jsii.Number(0)},
+			{Name: "Morning", Schedule: "cron(0 6 * * ? *)", MaxTaskCount: jsii.Number(4)},
+		},
+	}, nil)
+
+	assertions.Template_FromStack(stack, nil).HasResourceProperties(jsii.String("AWS::ApplicationAutoScaling::ScalableTarget"), map[string]interface{}{
+		"MinCapacity": 1,
+		"MaxCapacity": 4,
+		"ScheduledActions": []interface{}{
+			map[string]interface{}{
+				"ScheduledActionName":  "NightScheduledScaling",
+				"ScalableTargetAction": map[string]interface{}{"MinCapacity": 0, "MaxCapacity": 0},
+			},
+			map[string]interface{}{
+				"ScheduledActionName":  "MorningScheduledScaling",
+				"ScalableTargetAction": map[string]interface{}{"MinCapacity": assertions.Match_Absent(), "MaxCapacity": 4},
+			},
+		},
+	})
+}
+
+func TestTargetTrackingScalingLeavesOutZeroTargets(t *testing.T) {
+	stack := newTestStack()
+	service := newTestFargateService(stack)
+	configureServiceAutoScaling(service, &ServiceAutoScalingProps{
+		MinTaskCount:   1,
+		MaxTaskCount:   2,
+		CpuUtilization: TargetTrackingScalingProps{TargetValue: 60, ScaleInCooldownSeconds: 120},
+	}, nil)
+
+	template := assertions.Template_FromStack(stack, nil)
+	template.ResourceCountIs(jsii.String("AWS::ApplicationAutoScaling::ScalingPolicy"), jsii.Number(1))
+	template.HasResourceProperties(jsii.String("AWS::ApplicationAutoScaling::ScalingPolicy"), map[string]interface{}{
+		"TargetTrackingScalingPolicyConfiguration": map[string]interface{}{
+			"TargetValue":     60,
+			"ScaleInCooldown": 120,
+			"PredefinedMetricSpecification": map[string]interface{}{
+				"PredefinedMetricType": "ECSServiceAverageCPUUtilization",
+			},
+		},
+	})
+}
+
+func TestInvalidAutoScalingPanics(t *testing.T) {
+	step := StepScalingProps{
+		Metric:       SCALING_METRIC_CPU_UTILIZATION,
+		ScalingSteps: []appscaling.ScalingInterval{{Upper: jsii.Number(10), Change: jsii.Number(-1)}, {Lower: jsii.Number(80), Change: jsii.Number(1)}},
+	}
+	named := func(name string) StepScalingProps {
+		props := step
+		props.Name = name
+		return props
+	}
+
+	cases := map[string]struct {
+		props    ServiceAutoScalingProps
+		expected string
+	}{
+		"unnamed step scaling": {
+			ServiceAutoScalingProps{MinTaskCount: 1, MaxTaskCount: 2, StepScaling: []StepScalingProps{step}},
+			"step scaling policy requires a Name",
+		},
+		"duplicate step scaling": {
+			ServiceAutoScalingProps{MinTaskCount: 1, MaxTaskCount: 2, StepScaling: []StepScalingProps{named("Cpu"), named("Cpu")}},
+			"duplicate step scaling policy name Cpu",
+		},
+		"duplicate scheduled scaling": {
+			ServiceAutoScalingProps{MinTaskCount: 1, MaxTaskCount: 2, ScheduledActions: []ScheduledScalingProps{
+				{Name: "Night", Schedule: "cron(0 22 * * ? *)", MaxTaskCount: jsii.Number(1)},
+				{Name: "Night", Schedule: "cron(0 23 * * ? *)", MaxTaskCount: jsii.Number(0)},
+			}},
+			"duplicate scheduled scaling name Night",
+		},
+		"scheduled scaling without counts": {
+			ServiceAutoScalingProps{MinTaskCount: 1, MaxTaskCount: 2, ScheduledActions: []ScheduledScalingProps{{Name: "Night", Schedule: "cron(0 22 * * ? *)"}}},
+			"requires MinTaskCount or MaxTaskCount",
+		},
+		"scheduled scaling min above max": {
+			ServiceAutoScalingProps{MinTaskCount: 1, MaxTaskCount: 2, ScheduledActions: []ScheduledScalingProps{
+				{Name: "Night", Schedule: "cron(0 22 * * ? *)", MinTaskCount: jsii.Number(2), MaxTaskCount: jsii.Number(1)},
+			}},
+			"invalid task counts for scheduled scaling Night: min 2, max 1",
+		},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			service := newTestFargateService(newTestStack())
+			defer func() {
+				if r := recover(); r == nil || !strings.Contains(fmt.Sprint(r), c.expected) {
+					t.Fatalf("expected a panic containing %q, got %v", c.expected, r)
+				}
+			}()
+			configureServiceAutoScaling(service, &c.props, nil)
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package containerpatterns
+
+import (
+	ec2 "github.com/aws/aws-cdk-go/awscdk/v2/awsec2"
+	ecs "github.com/aws/aws-cdk-go/awscdk/v2/awsecs"
+	elb2 "github.com/aws/aws-cdk-go/awscdk/v2/awselasticloadbalancingv2"
+	servicediscovery "github.com/aws/aws-cdk-go/awscdk/v2/awsservicediscovery"
+	"github.com/aws/constructs-go/constructs/v10"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// ServiceCompute is what a service needs from the compute it runs on, a ContainerCompute in the same app can be passed
+// as it is. Services in other apps keep using the cluster name, listener ARN and namespace attributes instead.
+type ServiceCompute interface {
+	Cluster() ecs.ICluster
+	Vpc() ec2.IVpc
+	HttpsListener() elb2.IApplicationListener
+	CloudMapNamespace() servicediscovery.IPrivateDnsNamespace
+}
+
+// resolveServiceCluster returns the compute's cluster and VPC, or imports the cluster given by its attributes
+func resolveServiceCluster(scope constructs.Construct, compute ServiceCompute, props *ClusterProps) (ecs.ICluster, ec2.IVpc) {
+	if compute != nil {
+		if props.ClusterName != "" || props.Vpc.Id != "" || props.Vpc.IsDefault || props.Vpc.Vpc != nil || len(props.SecurityGroups) > 0 {
+			panic("cluster must be given either as a Compute or as ClusterProps, not both")
+		}
+		return compute.Cluster(), compute.Vpc()
+	}
+
+	vpc := LookupVpc(scope, jsii.String("Vpc"), &props.Vpc)
+	cluster := ecs.Cluster_FromClusterAttributes(scope, jsii.String("Cluster"), &ecs.ClusterAttributes{
+		ClusterName:    jsii.String(props.ClusterName),
+		Vpc:            vpc,
+		SecurityGroups: &props.SecurityGroups,
+	})
+	return cluster, vpc
+}
+
+// resolveServiceListener imports the listener by its ARN, without one the compute's HTTPS listener is used
+func resolveServiceListener(scope constructs.Construct, compute ServiceCompute, lbProps *LoadBalancerProps) elb2.IApplicationListener {
+	if lbProps.LoadBalancerListenerArn == "" && compute != nil {
+		return compute.HttpsListener()
+	}
+	if lbProps.LoadBalancerListenerArn == "" || lbProps.LoadBalancerSecurityGroupId == "" {
+		panic("load balanced service requires a Compute, or LoadBalancerListenerArn and LoadBalancerSecurityGroupId")
+	}
+
+	listener := elb2.ApplicationListener_FromApplicationListenerAttributes(scope, jsii.String("ALBListener"), &elb2.ApplicationListenerAttributes{
+		ListenerArn:   jsii.String(lbProps.LoadBalancerListenerArn),
+		SecurityGroup: ec2.SecurityGroup_FromLookupById(scope, jsii.String("ALBSecurityGroup"), jsii.String(lbProps.LoadBalancerSecurityGroupId)),
+	})
+	return listener
+}
+
+// resolveServiceCloudMapNamespace imports the namespace by its attributes, without them the compute's namespace is used
+func resolveServiceCloudMapNamespace(scope constructs.Construct, compute ServiceCompute, props *ServiceDiscoveryProps) servicediscovery.IPrivateDnsNamespace {
+	if props.NamespaceId == "" && compute != nil {
+		return compute.CloudMapNamespace()
+	}
+	return getCloudMapNamespaceService(scope, *props)
+}
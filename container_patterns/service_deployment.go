@@ -0,0 +1,223 @@
+package containerpatterns
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	cloudwatch "github.com/aws/aws-cdk-go/awscdk/v2/awscloudwatch"
+	codedeploy "github.com/aws/aws-cdk-go/awscdk/v2/awscodedeploy"
+	ec2 "github.com/aws/aws-cdk-go/awscdk/v2/awsec2"
+	ecs "github.com/aws/aws-cdk-go/awscdk/v2/awsecs"
+	elb2 "github.com/aws/aws-cdk-go/awscdk/v2/awselasticloadbalancingv2"
+	"github.com/aws/constructs-go/constructs/v10"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// custom types
+type (
+	DeploymentController string
+	TrafficShifting      string
+	CircuitBreakerMode   string
+)
+
+const (
+	DEPLOYMENT_CONTROLLER_ECS         DeploymentController = "ECS"
+	DEPLOYMENT_CONTROLLER_CODE_DEPLOY DeploymentController = "CODE_DEPLOY"
+	DEFAULT_DEPLOYMENT_CONTROLLER     DeploymentController = DEPLOYMENT_CONTROLLER_ECS
+)
+
+const (
+	TRAFFIC_SHIFTING_ALL_AT_ONCE TrafficShifting = "ALL_AT_ONCE"
+	TRAFFIC_SHIFTING_CANARY      TrafficShifting = "CANARY"
+	TRAFFIC_SHIFTING_LINEAR      TrafficShifting = "LINEAR"
+	DEFAULT_TRAFFIC_SHIFTING     TrafficShifting = TRAFFIC_SHIFTING_ALL_AT_ONCE
+)
+
+const (
+	CIRCUIT_BREAKER_MODE_ROLLBACK CircuitBreakerMode = "ROLLBACK"
+	CIRCUIT_BREAKER_MODE_ENABLED  CircuitBreakerMode = "ENABLED"
+	CIRCUIT_BREAKER_MODE_DISABLED CircuitBreakerMode = "DISABLED"
+	DEFAULT_CIRCUIT_BREAKER_MODE  CircuitBreakerMode = CIRCUIT_BREAKER_MODE_ROLLBACK
+)
+
+// ServiceDeploymentProps tunes rolling deployments, zero values keep the ECS and load balancer defaults. The circuit
+// breaker stops a deployment whose tasks keep failing to start, CIRCUIT_BREAKER_MODE_ENABLED leaves the failed
+// deployment in place instead of rolling it back. The grace period keeps load balancer health checks from stopping
+// tasks that are still warming up.
+type ServiceDeploymentProps struct {
+	MinHealthyPercent             float64
+	MaxHealthyPercent             float64
+	CircuitBreaker                CircuitBreakerMode
+	HealthCheckGracePeriodSeconds float64
+	AlarmNames                    []string
+	IsAlarmRollbackEnabled        bool
+	DeregistrationDelaySeconds    float64
+}
+
+// BlueGreenDeploymentProps configures CodeDeploy blue/green deployments. The test listener on the shared load balancer
+// gets a rule with the production conditions, CodeDeploy points it at the replacement tasks first so they can be
+// tested before production traffic is shifted.
+//
+// CANARY shifts TrafficShiftPercent once and the rest after TrafficShiftIntervalMinutes, LINEAR shifts
+// TrafficShiftPercent every TrafficShiftIntervalMinutes. A deployment rolls back when it fails, is stopped or one of
+// the alarms fires.
+//
+// TestRulePriority defaults to the production rule's priority, which only works because the test rule lives on its
+// own listener. Set it when TestListenerArn carries rules of other services that may already use that priority.
+//
+// Once a service uses DEPLOYMENT_CONTROLLER_CODE_DEPLOY, CloudFormation can no longer roll out task definition
+// changes to it, new revisions have to be deployed through CodeDeploy.
+type BlueGreenDeploymentProps struct {
+	ApplicationName             string
+	DeploymentGroupName         string
+	TestListenerArn             string
+	TestRulePriority            float64
+	TrafficShifting             TrafficShifting
+	TrafficShiftPercent         float64
+	TrafficShiftIntervalMinutes float64
+	AlarmArns                   []string
+	TerminationWaitTimeMinutes  float64
+}
+
+// createServiceDeploymentController returns nil for ECS, the controller is set together with the circuit breaker
+func createServiceDeploymentController(controller DeploymentController) *ecs.DeploymentController {
+	switch controller {
+	case "", DEPLOYMENT_CONTROLLER_ECS:
+		return nil
+	case DEPLOYMENT_CONTROLLER_CODE_DEPLOY:
+		return &ecs.DeploymentController{Type: ecs.DeploymentControllerType_CODE_DEPLOY}
+	default:
+		panic(fmt.Sprintf("invalid deployment controller %q", controller))
+	}
+}
+
+// createServiceCircuitBreaker returns nil for CodeDeploy, which rolls back deployments itself
+func createServiceCircuitBreaker(controller DeploymentController, mode CircuitBreakerMode) *ecs.DeploymentCircuitBreaker {
+	if controller == DEPLOYMENT_CONTROLLER_CODE_DEPLOY {
+		return nil
+	}
+	switch mode {
+	case "", CIRCUIT_BREAKER_MODE_ROLLBACK:
+		return &ecs.DeploymentCircuitBreaker{
+			Rollback: jsii.Bool(true),
+		}
+	case CIRCUIT_BREAKER_MODE_ENABLED:
+		return &ecs.DeploymentCircuitBreaker{
+			Rollback: jsii.Bool(false),
+		}
+	case CIRCUIT_BREAKER_MODE_DISABLED:
+		return nil
+	default:
+		panic(fmt.Sprintf("invalid circuit breaker mode %q", mode))
+	}
+}
+
+// configureServiceDeploymentAlarms makes ECS watch the alarms during rolling deployments, the pinned CDK version has
+// no property for them yet.
+func configureServiceDeploymentAlarms(service ecs.BaseService, controller DeploymentController, props *ServiceDeploymentProps) {
+	if len(props.AlarmNames) == 0 {
+		return
+	}
+	if controller == DEPLOYMENT_CONTROLLER_CODE_DEPLOY {
+		panic("deployment alarms only apply to the ECS deployment controller, use BlueGreenDeployment.AlarmArns with CodeDeploy")
+	}
+	service.Node().DefaultChild().(awscdk.CfnResource).AddPropertyOverride(jsii.String("DeploymentConfiguration.Alarms"), map[string]interface{}{
+		"AlarmNames": props.AlarmNames,
+		"Enable":     true,
+		"Rollback":   props.IsAlarmRollbackEnabled,
+	})
+}
+
+// configureServiceBlueGreenDeployment adds the replacement target group and test listener rule next to the production
+// ones and creates the CodeDeploy deployment group that shifts traffic between them.
+func configureServiceBlueGreenDeployment(scope constructs.Construct, service ecs.BaseService, props *BlueGreenDeploymentProps, vpc ec2.IVpc, targetType elb2.TargetType, lbProps *LoadBalancerProps, listenerProps *LoadBalancerListenerProps, blueTargetGroup elb2.ApplicationTargetGroup, listener elb2.IApplicationListener, deregistrationDelaySeconds float64) codedeploy.EcsDeploymentGroup {
+	if props.TestListenerArn == "" {
+		panic("blue/green deployment requires TestListenerArn")
+	}
+
+	greenTargetGroup := createServiceTargetGroup(scope, jsii.String("GreenApplicationTargetGroup"), vpc, targetType, lbProps, deregistrationDelaySeconds)
+
+	listenerSecurityGroups := *listener.Connections().SecurityGroups()
+	if len(listenerSecurityGroups) == 0 {
+		panic("blue/green deployment requires the production listener's load balancer security group for the test listener")
+	}
+	testListener := elb2.ApplicationListener_FromApplicationListenerAttributes(scope, jsii.String("ALBTestListener"), &elb2.ApplicationListenerAttributes{
+		ListenerArn:   jsii.String(props.TestListenerArn),
+		SecurityGroup: listenerSecurityGroups[0],
+	})
+	// the test listener is a separate listener, so the production priority is free on it unless another service took it
+	testRulePriority := props.TestRulePriority
+	if testRulePriority == 0 {
+		testRulePriority = listenerProps.RulePriority
+	}
+	createServiceListenerRule(
+		scope,
+		jsii.String("ALBTestListenerRule"),
+		testListener,
+		testRulePriority,
+		listenerProps.PriorityAllocation,
+		resolveServiceListenerConditions(listenerProps),
+		elb2.ListenerAction_Forward(&[]elb2.IApplicationTargetGroup{blueTargetGroup}, &elb2.ForwardOptions{}),
+	)
+
+	alarms := []cloudwatch.IAlarm{}
+	for index, alarmArn := range props.AlarmArns {
+		alarms = append(alarms, cloudwatch.Alarm_FromAlarmArn(scope, jsii.String(fmt.Sprintf("DeploymentAlarm%d", index)), jsii.String(alarmArn)))
+	}
+
+	var terminationWaitTime awscdk.Duration = nil
+	if props.TerminationWaitTimeMinutes > 0 {
+		terminationWaitTime = awscdk.Duration_Minutes(jsii.Number(props.TerminationWaitTimeMinutes))
+	}
+
+	deploymentGroup := codedeploy.NewEcsDeploymentGroup(scope, jsii.String("DeploymentGroup"), &codedeploy.EcsDeploymentGroupProps{
+		Application: codedeploy.NewEcsApplication(scope, jsii.String("DeploymentApplication"), &codedeploy.EcsApplicationProps{
+			ApplicationName: optionalString(props.ApplicationName),
+		}),
+		DeploymentGroupName: optionalString(props.DeploymentGroupName),
+		Service:             service,
+		DeploymentConfig:    createServiceDeploymentConfig(scope, jsii.String("DeploymentConfig"), props),
+		BlueGreenDeploymentConfig: &codedeploy.EcsBlueGreenDeploymentConfig{
+			BlueTargetGroup:     blueTargetGroup,
+			GreenTargetGroup:    greenTargetGroup,
+			Listener:            listener,
+			TestListener:        testListener,
+			TerminationWaitTime: terminationWaitTime,
+		},
+		Alarms: &alarms,
+		AutoRollback: &codedeploy.AutoRollbackConfig{
+			FailedDeployment:  jsii.Bool(true),
+			StoppedDeployment: jsii.Bool(true),
+			DeploymentInAlarm: jsii.Bool(len(alarms) > 0),
+		},
+	})
+	return deploymentGroup
+}
+
+func createServiceDeploymentConfig(scope constructs.Construct, id *string, props *BlueGreenDeploymentProps) codedeploy.IEcsDeploymentConfig {
+	switch props.TrafficShifting {
+	case "", TRAFFIC_SHIFTING_ALL_AT_ONCE:
+		return codedeploy.EcsDeploymentConfig_ALL_AT_ONCE()
+	case TRAFFIC_SHIFTING_CANARY, TRAFFIC_SHIFTING_LINEAR:
+		if props.TrafficShiftPercent <= 0 || props.TrafficShiftPercent >= 100 || props.TrafficShiftIntervalMinutes <= 0 {
+			panic(fmt.Sprintf("%s traffic shifting requires TrafficShiftPercent between 1 and 99 and TrafficShiftIntervalMinutes", props.TrafficShifting))
+		}
+		var trafficRouting codedeploy.TrafficRouting
+		if props.TrafficShifting == TRAFFIC_SHIFTING_CANARY {
+			trafficRouting = codedeploy.TrafficRouting_TimeBasedCanary(&codedeploy.TimeBasedCanaryTrafficRoutingProps{
+				Percentage: jsii.Number(props.TrafficShiftPercent),
+				Interval:   awscdk.Duration_Minutes(jsii.Number(props.TrafficShiftIntervalMinutes)),
+			})
+		} else {
+			trafficRouting = codedeploy.TrafficRouting_TimeBasedLinear(&codedeploy.TimeBasedLinearTrafficRoutingProps{
+				Percentage: jsii.Number(props.TrafficShiftPercent),
+				Interval:   awscdk.Duration_Minutes(jsii.Number(props.TrafficShiftIntervalMinutes)),
+			})
+		}
+		return codedeploy.NewEcsDeploymentConfig(scope, id, &codedeploy.EcsDeploymentConfigProps{
+			TrafficRouting: trafficRouting,
+		})
+	default:
+		panic(fmt.Sprintf("invalid traffic shifting %q", props.TrafficShifting))
+	}
+}
@@ -0,0 +1,71 @@
+package containerpatterns
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/assertions"
+	ec2 "github.com/aws/aws-cdk-go/awscdk/v2/awsec2"
+	ecs "github.com/aws/aws-cdk-go/awscdk/v2/awsecs"
+	"github.com/aws/jsii-runtime-go"
+)
+
+func newTestFargateService(stack awscdk.Stack) ecs.FargateService {
+	vpc := ec2.NewVpc(stack, jsii.String("Vpc"), &ec2.VpcProps{})
+	cluster := ecs.NewCluster(stack, jsii.String("Cluster"), &ecs.ClusterProps{Vpc: vpc})
+	taskDef := ecs.NewFargateTaskDefinition(stack, jsii.String("TaskDefinition"), &ecs.FargateTaskDefinitionProps{})
+	taskDef.AddContainer(jsii.String("app"), &ecs.ContainerDefinitionOptions{
+		Image: ecs.ContainerImage_FromRegistry(jsii.String("nginx"), nil),
+	})
+	return ecs.NewFargateService(stack, jsii.String("Service"), &ecs.FargateServiceProps{
+		Cluster:           cluster,
+		TaskDefinition:    taskDef,
+		MinHealthyPercent: jsii.Number(50),
+	})
+}
+
+func TestDeploymentAlarmsAreAddedToDeploymentConfiguration(t *testing.T) {
+	stack := newTestStack()
+	service := newTestFargateService(stack)
+	configureServiceDeploymentAlarms(service, DEPLOYMENT_CONTROLLER_ECS, &ServiceDeploymentProps{
+		AlarmNames:             []string{"HighErrorRate", "HighLatency"},
+		IsAlarmRollbackEnabled: true,
+	})
+
+	assertions.Template_FromStack(stack, nil).HasResourceProperties(jsii.String("AWS::ECS::Service"), map[string]interface{}{
+		"DeploymentConfiguration": map[string]interface{}{
+			"MinimumHealthyPercent": 50,
+			"Alarms": map[string]interface{}{
+				"AlarmNames": []interface{}{"HighErrorRate", "HighLatency"},
+				"Enable":     true,
+				"Rollback":   true,
+			},
+		},
+	})
+}
+
+func TestDeploymentAlarmsAreLeftOutWithoutAlarmNames(t *testing.T) {
+	stack := newTestStack()
+	service := newTestFargateService(stack)
+	configureServiceDeploymentAlarms(service, DEPLOYMENT_CONTROLLER_ECS, &ServiceDeploymentProps{IsAlarmRollbackEnabled: true})
+
+	assertions.Template_FromStack(stack, nil).HasResourceProperties(jsii.String("AWS::ECS::Service"), map[string]interface{}{
+		"DeploymentConfiguration": map[string]interface{}{
+			"Alarms": assertions.Match_Absent(),
+		},
+	})
+}
+
+func TestDeploymentAlarmsPanicWithCodeDeploy(t *testing.T) {
+	stack := newTestStack()
+	service := newTestFargateService(stack)
+
+	defer func() {
+		if r := recover(); r == nil || !strings.Contains(fmt.Sprint(r), "BlueGreenDeployment.AlarmArns") {
+			t.Fatalf("expected deployment alarms to be rejected, got %v", r)
+		}
+	}()
+	configureServiceDeploymentAlarms(service, DEPLOYMENT_CONTROLLER_CODE_DEPLOY, &ServiceDeploymentProps{AlarmNames: []string{"HighErrorRate"}})
+}
@@ -0,0 +1,359 @@
+package containerpatterns
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	ecs "github.com/aws/aws-cdk-go/awscdk/v2/awsecs"
+	iam "github.com/aws/aws-cdk-go/awscdk/v2/awsiam"
+	"github.com/aws/constructs-go/constructs/v10"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// custom types
+type (
+	VolumeKind    string
+	EbsVolumeType string
+)
+
+const (
+	VOLUME_KIND_LEGACY VolumeKind = ""
+	VOLUME_KIND_EFS    VolumeKind = "EFS"
+	VOLUME_KIND_HOST   VolumeKind = "HOST"
+	VOLUME_KIND_DOCKER VolumeKind = "DOCKER"
+	VOLUME_KIND_EBS    VolumeKind = "EBS"
+)
+
+const (
+	EBS_VOLUME_TYPE_GP3     EbsVolumeType = "gp3"
+	EBS_VOLUME_TYPE_IO2     EbsVolumeType = "io2"
+	DEFAULT_EBS_VOLUME_TYPE EbsVolumeType = EBS_VOLUME_TYPE_GP3
+)
+
+const (
+	DEFAULT_DOCKER_VOLUME_DRIVER     string  = "local"
+	REXRAY_VOLUME_DRIVER             string  = "rexray/ebs"
+	REXRAY_VOLUME_TYPE               string  = "gp2"
+	DEFAULT_EBS_VOLUME_SIZE_GIB      float64 = 20
+	DEFAULT_EBS_FILE_SYSTEM_TYPE     string  = "ext4"
+	EFS_TRANSIT_ENCRYPTION_PORT      float64 = 2049
+	ECS_VOLUME_INFRASTRUCTURE_POLICY string  = "service-role/AmazonECSInfrastructureRolePolicyForVolumes"
+)
+
+// Volume is a task volume of the given Kind, only the props block matching the kind is read. A task can have at most
+// one EBS volume, ECS creates it when the task starts and deletes it when the task stops. Without a Kind the volume is
+// a shared rexray/ebs Docker volume of Size GiB as before, its instances need IsRexrayPluginEnabled.
+type Volume struct {
+	Name   string
+	Kind   VolumeKind
+	Size   string
+	Efs    EfsVolumeProps
+	Host   HostVolumeProps
+	Docker DockerVolumeProps
+	Ebs    EbsVolumeProps
+}
+
+// EfsVolumeProps mounts an EFS file system, IAM authorization requires transit encryption and makes the task role
+// the NFS client identity.
+type EfsVolumeProps struct {
+	FileSystemId               string
+	AccessPointId              string
+	RootDirectory              string
+	IsTransitEncryptionEnabled bool
+	IsIamAuthorizationEnabled  bool
+	IsReadOnly                 bool
+}
+
+// HostVolumeProps bind mounts SourcePath from the container instance, an empty SourcePath gives a volume on the
+// task's own storage that lives as long as the task.
+type HostVolumeProps struct {
+	SourcePath string
+}
+
+type DockerVolumeProps struct {
+	Driver                 string
+	Scope                  ecs.Scope
+	IsAutoprovisionEnabled bool
+	DriverOpts             map[string]string
+	Labels                 map[string]string
+}
+
+// EbsVolumeProps configures the encrypted EBS volume attached to each task, the account's default EBS key is used
+// unless KmsKeyArn is set.
+type EbsVolumeProps struct {
+	SizeGiB        float64
+	VolumeType     EbsVolumeType
+	Iops           float64
+	Throughput     float64
+	KmsKeyArn      string
+	SnapshotId     string
+	FileSystemType string
+}
+
+// addTaskDefinitionVolumes adds the volumes to the task definition and grants the task role what its EFS volumes need.
+// The EBS volume, if any, is returned so the service can configure it at launch.
+func addTaskDefinitionVolumes(taskDef ecs.TaskDefinition, volumes []Volume, isFargate bool) *Volume {
+	var ebsVolume *Volume = nil
+	for index := range volumes {
+		volume := &volumes[index]
+		vol := &ecs.Volume{
+			Name: jsii.String(volume.Name),
+		}
+
+		switch volume.Kind {
+		case VOLUME_KIND_LEGACY:
+			if isFargate {
+				panic("volume " + volume.Name + " cannot use the rexray/ebs Docker volume driver on Fargate")
+			}
+			vol.DockerVolumeConfiguration = createRexrayVolumeConfiguration(volume)
+		case VOLUME_KIND_EFS:
+			vol.EfsVolumeConfiguration = createEfsVolumeConfiguration(volume)
+			if volume.Efs.IsIamAuthorizationEnabled {
+				taskDef.AddToTaskRolePolicy(createEfsClientPolicyStatement(taskDef, &volume.Efs))
+			}
+		case VOLUME_KIND_HOST:
+			if isFargate && volume.Host.SourcePath != "" {
+				panic("volume " + volume.Name + " cannot bind mount a host path on Fargate")
+			}
+			if volume.Host.SourcePath != "" {
+				vol.Host = &ecs.Host{SourcePath: jsii.String(volume.Host.SourcePath)}
+			}
+		case VOLUME_KIND_DOCKER:
+			if isFargate {
+				panic("volume " + volume.Name + " cannot use a Docker volume driver on Fargate")
+			}
+			vol.DockerVolumeConfiguration = createDockerVolumeConfiguration(&volume.Docker)
+		case VOLUME_KIND_EBS:
+			if ebsVolume != nil {
+				panic("volume " + volume.Name + " is a second EBS volume, a task can only have one")
+			}
+			ebsVolume = volume
+		default:
+			panic(fmt.Sprintf("invalid kind %q for volume %s", volume.Kind, volume.Name))
+		}
+
+		taskDef.AddVolume(vol)
+		if volume.Kind == VOLUME_KIND_EBS {
+			configureVolumeAtLaunch(taskDef, volume.Name)
+		}
+	}
+	return ebsVolume
+}
+
+// configureVolumeAtLaunch marks the named volume as configured by the service. The task definition only declares the
+// volume and the override has to address it by its position, which is only known once all volumes are added.
+func configureVolumeAtLaunch(taskDef ecs.TaskDefinition, name string) {
+	awscdk.Aspects_Of(taskDef).Add(&volumeAtLaunchAspect{
+		cfnTaskDef: taskDef.Node().DefaultChild().(ecs.CfnTaskDefinition),
+		name:       name,
+	})
+}
+
+type volumeAtLaunchAspect struct {
+	cfnTaskDef ecs.CfnTaskDefinition
+	name       string
+}
+
+func (a *volumeAtLaunchAspect) Visit(node constructs.IConstruct) {
+	if *node.Node().Path() != *a.cfnTaskDef.Node().Path() {
+		return
+	}
+	volumes, _ := awscdk.Stack_Of(node).Resolve(a.cfnTaskDef.Volumes()).([]interface{})
+	for index, volume := range volumes {
+		if volume.(map[string]interface{})["name"] == a.name {
+			a.cfnTaskDef.AddPropertyOverride(jsii.String(fmt.Sprintf("Volumes.%d.ConfiguredAtLaunch", index)), true)
+			return
+		}
+	}
+	panic("task definition has no volume " + a.name + " to configure at launch")
+}
+
+func createEfsVolumeConfiguration(volume *Volume) *ecs.EfsVolumeConfiguration {
+	props := &volume.Efs
+	if props.FileSystemId == "" {
+		panic("EFS volume " + volume.Name + " requires FileSystemId")
+	}
+	if props.IsIamAuthorizationEnabled && !props.IsTransitEncryptionEnabled {
+		panic("EFS volume " + volume.Name + " requires transit encryption for IAM authorization")
+	}
+	if props.AccessPointId != "" && !props.IsTransitEncryptionEnabled {
+		panic("EFS volume " + volume.Name + " requires transit encryption to use an access point")
+	}
+
+	config := &ecs.EfsVolumeConfiguration{
+		FileSystemId:  jsii.String(props.FileSystemId),
+		RootDirectory: optionalString(props.RootDirectory),
+	}
+	if props.IsTransitEncryptionEnabled {
+		config.TransitEncryption = jsii.String("ENABLED")
+		config.TransitEncryptionPort = jsii.Number(EFS_TRANSIT_ENCRYPTION_PORT)
+	}
+	if props.AccessPointId != "" || props.IsIamAuthorizationEnabled {
+		iamAuthorization := "DISABLED"
+		if props.IsIamAuthorizationEnabled {
+			iamAuthorization = "ENABLED"
+		}
+		config.AuthorizationConfig = &ecs.AuthorizationConfig{
+			AccessPointId: optionalString(props.AccessPointId),
+			Iam:           jsii.String(iamAuthorization),
+		}
+	}
+	return config
+}
+
+// createEfsClientPolicyStatement lets the task role mount the file system, through the access point when one is set
+func createEfsClientPolicyStatement(taskDef ecs.TaskDefinition, props *EfsVolumeProps) iam.PolicyStatement {
+	actions := []*string{
+		jsii.String("elasticfilesystem:ClientMount"),
+	}
+	if !props.IsReadOnly {
+		actions = append(actions, jsii.String("elasticfilesystem:ClientWrite"))
+	}
+
+	stack := awscdk.Stack_Of(taskDef)
+	statement := iam.NewPolicyStatement(&iam.PolicyStatementProps{
+		Effect:  iam.Effect_ALLOW,
+		Actions: &actions,
+		Resources: &[]*string{
+			awscdk.Arn_Format(&awscdk.ArnComponents{
+				Service:      jsii.String("elasticfilesystem"),
+				Resource:     jsii.String("file-system"),
+				ResourceName: jsii.String(props.FileSystemId),
+			}, stack),
+		},
+	})
+	if props.AccessPointId != "" {
+		statement.AddCondition(jsii.String("StringEquals"), map[string]interface{}{
+			"elasticfilesystem:AccessPointArn": awscdk.Arn_Format(&awscdk.ArnComponents{
+				Service:      jsii.String("elasticfilesystem"),
+				Resource:     jsii.String("access-point"),
+				ResourceName: jsii.String(props.AccessPointId),
+			}, stack),
+		})
+	}
+	return statement
+}
+
+func createRexrayVolumeConfiguration(volume *Volume) *ecs.DockerVolumeConfiguration {
+	return &ecs.DockerVolumeConfiguration{
+		Driver:        jsii.String(REXRAY_VOLUME_DRIVER),
+		Scope:         ecs.Scope_SHARED,
+		Autoprovision: jsii.Bool(true),
+		DriverOpts: &map[string]*string{
+			"volumetype": jsii.String(REXRAY_VOLUME_TYPE),
+			"size":       jsii.String(volume.Size),
+		},
+	}
+}
+
+func createDockerVolumeConfiguration(props *DockerVolumeProps) *ecs.DockerVolumeConfiguration {
+	driver := props.Driver
+	if driver == "" {
+		driver = DEFAULT_DOCKER_VOLUME_DRIVER
+	}
+	scope := props.Scope
+	if scope == "" {
+		scope = ecs.Scope_TASK
+	}
+
+	config := &ecs.DockerVolumeConfiguration{
+		Driver:     jsii.String(driver),
+		Scope:      scope,
+		DriverOpts: convertStringMap(props.DriverOpts),
+		Labels:     convertStringMap(props.Labels),
+	}
+	// autoprovisioning only applies to shared volumes
+	if scope == ecs.Scope_SHARED {
+		config.Autoprovision = jsii.Bool(props.IsAutoprovisionEnabled)
+	}
+	return config
+}
+
+// configureServiceEbsVolume hands ECS the configuration of the task's EBS volume together with the infrastructure
+// role it uses to create, attach and delete the volume.
+func configureServiceEbsVolume(scope constructs.Construct, service ecs.BaseService, volume *Volume) {
+	props := &volume.Ebs
+
+	infrastructureRole := iam.NewRole(scope, jsii.String("EbsVolumeInfrastructureRole"), &iam.RoleProps{
+		AssumedBy: iam.NewServicePrincipal(jsii.String("ecs.amazonaws.com"), &iam.ServicePrincipalOpts{}),
+		ManagedPolicies: &[]iam.IManagedPolicy{
+			iam.ManagedPolicy_FromAwsManagedPolicyName(jsii.String(ECS_VOLUME_INFRASTRUCTURE_POLICY)),
+		},
+	})
+	if props.KmsKeyArn != "" {
+		infrastructureRole.AddToPrincipalPolicy(iam.NewPolicyStatement(&iam.PolicyStatementProps{
+			Effect: iam.Effect_ALLOW,
+			Actions: &[]*string{
+				jsii.String("kms:CreateGrant"),
+				jsii.String("kms:Decrypt"),
+				jsii.String("kms:DescribeKey"),
+				jsii.String("kms:GenerateDataKeyWithoutPlaintext"),
+				jsii.String("kms:ReEncrypt*"),
+			},
+			Resources: &[]*string{
+				jsii.String(props.KmsKeyArn),
+			},
+		}))
+	}
+
+	volumeType := props.VolumeType
+	switch volumeType {
+	case "":
+		volumeType = DEFAULT_EBS_VOLUME_TYPE
+	case EBS_VOLUME_TYPE_GP3:
+	case EBS_VOLUME_TYPE_IO2:
+		if props.Iops == 0 {
+			panic("EBS volume " + volume.Name + " of type io2 requires Iops")
+		}
+	default:
+		panic(fmt.Sprintf("invalid type %q for EBS volume %s", volumeType, volume.Name))
+	}
+	size := props.SizeGiB
+	if size == 0 && props.SnapshotId == "" {
+		size = DEFAULT_EBS_VOLUME_SIZE_GIB
+	}
+	fileSystemType := props.FileSystemType
+	if fileSystemType == "" {
+		fileSystemType = DEFAULT_EBS_FILE_SYSTEM_TYPE
+	}
+
+	managedEbsVolume := map[string]interface{}{
+		"RoleArn":        infrastructureRole.RoleArn(),
+		"VolumeType":     string(volumeType),
+		"Encrypted":      true,
+		"FilesystemType": fileSystemType,
+	}
+	if size > 0 {
+		managedEbsVolume["SizeInGiB"] = size
+	}
+	if props.Iops > 0 {
+		managedEbsVolume["Iops"] = props.Iops
+	}
+	if props.Throughput > 0 {
+		managedEbsVolume["Throughput"] = props.Throughput
+	}
+	if props.KmsKeyArn != "" {
+		managedEbsVolume["KmsKeyId"] = props.KmsKeyArn
+	}
+	if props.SnapshotId != "" {
+		managedEbsVolume["SnapshotId"] = props.SnapshotId
+	}
+
+	service.Node().DefaultChild().(awscdk.CfnResource).AddPropertyOverride(jsii.String("VolumeConfigurations"), []interface{}{
+		map[string]interface{}{
+			"Name":             volume.Name,
+			"ManagedEBSVolume": managedEbsVolume,
+		},
+	})
+}
+
+func convertStringMap(m map[string]string) *map[string]*string {
+	if len(m) == 0 {
+		return nil
+	}
+	converted := map[string]*string{}
+	for key, value := range m {
+		converted[key] = jsii.String(value)
+	}
+	return &converted
+}
@@ -0,0 +1,56 @@
+package containerpatterns
+
+import (
+	"testing"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2/assertions"
+	ecs "github.com/aws/aws-cdk-go/awscdk/v2/awsecs"
+	"github.com/aws/jsii-runtime-go"
+)
+
+func TestEbsVolumeIsConfiguredAtLaunchByName(t *testing.T) {
+	stack := newTestStack()
+	taskDef := ecs.NewFargateTaskDefinition(stack, jsii.String("TaskDefinition"), &ecs.FargateTaskDefinitionProps{})
+
+	// volumes added before and after the declared ones, as file system volumes are
+	taskDef.AddVolume(&ecs.Volume{Name: jsii.String("before")})
+	addTaskDefinitionVolumes(taskDef, []Volume{
+		{Name: "tmp", Kind: VOLUME_KIND_HOST},
+		{Name: "data", Kind: VOLUME_KIND_EBS},
+		{Name: "shared", Kind: VOLUME_KIND_EFS, Efs: EfsVolumeProps{FileSystemId: "fs-12345"}},
+	}, true)
+	taskDef.AddVolume(&ecs.Volume{Name: jsii.String("after")})
+
+	notConfiguredAtLaunch := func(name string) map[string]interface{} {
+		return map[string]interface{}{"Name": name, "ConfiguredAtLaunch": assertions.Match_Absent()}
+	}
+	assertions.Template_FromStack(stack, nil).HasResourceProperties(jsii.String("AWS::ECS::TaskDefinition"), map[string]interface{}{
+		"Volumes": []interface{}{
+			notConfiguredAtLaunch("before"),
+			notConfiguredAtLaunch("tmp"),
+			map[string]interface{}{"Name": "data", "ConfiguredAtLaunch": true},
+			notConfiguredAtLaunch("shared"),
+			notConfiguredAtLaunch("after"),
+		},
+	})
+}
+
+func TestVolumeWithoutKindIsRexrayVolume(t *testing.T) {
+	stack := newTestStack()
+	taskDef := ecs.NewEc2TaskDefinition(stack, jsii.String("TaskDefinition"), &ecs.Ec2TaskDefinitionProps{})
+	addTaskDefinitionVolumes(taskDef, []Volume{{Name: "data", Size: "10"}}, false)
+
+	assertions.Template_FromStack(stack, nil).HasResourceProperties(jsii.String("AWS::ECS::TaskDefinition"), map[string]interface{}{
+		"Volumes": []interface{}{
+			map[string]interface{}{
+				"Name": "data",
+				"DockerVolumeConfiguration": map[string]interface{}{
+					"Driver":        REXRAY_VOLUME_DRIVER,
+					"Scope":         "shared",
+					"Autoprovision": true,
+					"DriverOpts":    map[string]interface{}{"volumetype": REXRAY_VOLUME_TYPE, "size": "10"},
+				},
+			},
+		},
+	})
+}
@@ -0,0 +1,74 @@
+package containerpatterns
+
+import (
+	ec2 "github.com/aws/aws-cdk-go/awscdk/v2/awsec2"
+	"github.com/aws/constructs-go/constructs/v10"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// constants
+const (
+	DEFAULT_VPC_ENDPOINT_SUBNET_TYPE ec2.SubnetType = DEFAULT_ASG_SUBNET_TYPE
+)
+
+// ContainerComputeVpcEndpointsProps places the endpoints container instances need to join the cluster, pull images,
+// ship logs and accept Session Manager sessions without a route to the internet. Without a SubnetType the endpoints
+// follow the subnets of the autoscaling groups: the S3 gateway is added to the route tables of every group's subnets
+// and the interface endpoints are placed in the first group's subnets, where all instances in the VPC can reach them.
+type ContainerComputeVpcEndpointsProps struct {
+	SubnetType ec2.SubnetType
+}
+
+// interface endpoints created for container instances, keyed by construct ID
+var containerComputeInterfaceEndpoints = []struct {
+	id      string
+	service func() ec2.InterfaceVpcEndpointAwsService
+}{
+	{"Ecs", ec2.InterfaceVpcEndpointAwsService_ECS},
+	{"EcsAgent", ec2.InterfaceVpcEndpointAwsService_ECS_AGENT},
+	{"EcsTelemetry", ec2.InterfaceVpcEndpointAwsService_ECS_TELEMETRY},
+	{"EcrApi", ec2.InterfaceVpcEndpointAwsService_ECR},
+	{"EcrDocker", ec2.InterfaceVpcEndpointAwsService_ECR_DOCKER},
+	{"Logs", ec2.InterfaceVpcEndpointAwsService_CLOUDWATCH_LOGS},
+	{"Ssm", ec2.InterfaceVpcEndpointAwsService_SSM},
+	{"SsmMessages", ec2.InterfaceVpcEndpointAwsService_SSM_MESSAGES},
+	{"Ec2Messages", ec2.InterfaceVpcEndpointAwsService_EC2_MESSAGES},
+}
+
+// createVpcEndpoints groups the endpoints under one construct so instances can be made to wait for all of them
+func createVpcEndpoints(scope constructs.Construct, id *string, props *ContainerComputeVpcEndpointsProps, asgProps []*ContainerComputeAsgProps, vpc ec2.IVpc) constructs.Construct {
+	this := constructs.NewConstruct(scope, id)
+
+	subnetSelections := []*ec2.SubnetSelection{}
+	if props.SubnetType != "" {
+		subnetSelections = append(subnetSelections, &ec2.SubnetSelection{SubnetType: props.SubnetType})
+	} else {
+		for _, asg := range asgProps {
+			subnetSelections = append(subnetSelections, createAsgSubnetSelection(this, asg))
+		}
+	}
+	if len(subnetSelections) == 0 {
+		subnetSelections = append(subnetSelections, &ec2.SubnetSelection{SubnetType: DEFAULT_VPC_ENDPOINT_SUBNET_TYPE})
+	}
+
+	// ECR stores image layers in S3, the gateway endpoint is added to the route tables of the selected subnets
+	ec2.NewGatewayVpcEndpoint(this, jsii.String("S3GatewayEndpoint"), &ec2.GatewayVpcEndpointProps{
+		Vpc:     vpc,
+		Service: ec2.GatewayVpcEndpointAwsService_S3(),
+		Subnets: &subnetSelections,
+	})
+
+	interfaceSubnets := *subnetSelections[0]
+	interfaceSubnets.OnePerAz = jsii.Bool(true)
+	for _, endpoint := range containerComputeInterfaceEndpoints {
+		ec2.NewInterfaceVpcEndpoint(this, jsii.String(endpoint.id+"InterfaceEndpoint"), &ec2.InterfaceVpcEndpointProps{
+			Vpc:               vpc,
+			Service:           endpoint.service(),
+			Subnets:           &interfaceSubnets,
+			PrivateDnsEnabled: jsii.Bool(true),
+			Open:              jsii.Bool(true),
+		})
+	}
+
+	return this
+}
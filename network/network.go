@@ -1,6 +1,13 @@
 package network
 
+import (
+	ec2 "github.com/aws/aws-cdk-go/awscdk/v2/awsec2"
+)
+
+// VpcProps selects the VPC a pattern runs in, either one to look up by Id (or IsDefault)
+// or an existing Vpc such as the one created by NewVpc. Exactly one of the two must be given.
 type VpcProps struct {
 	Id        string
 	IsDefault bool
+	Vpc       ec2.IVpc
 }
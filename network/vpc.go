@@ -0,0 +1,261 @@
+package network
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	ec2 "github.com/aws/aws-cdk-go/awscdk/v2/awsec2"
+	cloudwatchlogs "github.com/aws/aws-cdk-go/awscdk/v2/awslogs"
+	s3 "github.com/aws/aws-cdk-go/awscdk/v2/awss3"
+	"github.com/aws/constructs-go/constructs/v10"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// custom types
+type (
+	NatMode            string
+	FlowLogDestination string
+)
+
+const (
+	NAT_MODE_GATEWAY  NatMode = "GATEWAY"
+	NAT_MODE_INSTANCE NatMode = "INSTANCE"
+	DEFAULT_NAT_MODE  NatMode = NAT_MODE_GATEWAY
+)
+
+const (
+	FLOW_LOG_DESTINATION_CLOUDWATCH_LOGS FlowLogDestination = "CLOUDWATCH_LOGS"
+	FLOW_LOG_DESTINATION_S3              FlowLogDestination = "S3"
+	DEFAULT_FLOW_LOG_DESTINATION         FlowLogDestination = FLOW_LOG_DESTINATION_CLOUDWATCH_LOGS
+)
+
+const (
+	DEFAULT_VPC_CIDR                  string                       = "10.0.0.0/16"
+	DEFAULT_VPC_MAX_AZS               float64                      = 2
+	DEFAULT_SUBNET_CIDR_MASK          float64                      = 24
+	DEFAULT_NAT_INSTANCE_CLASS        ec2.InstanceClass            = ec2.InstanceClass_T3
+	DEFAULT_NAT_INSTANCE_SIZE         ec2.InstanceSize             = ec2.InstanceSize_NANO
+	DEFAULT_FLOW_LOG_RETENTION        cloudwatchlogs.RetentionDays = cloudwatchlogs.RetentionDays_ONE_MONTH
+	DEFAULT_FLOW_LOG_TRAFFIC_TYPE     ec2.FlowLogTrafficType       = ec2.FlowLogTrafficType_ALL
+	DEFAULT_PUBLIC_SUBNET_TIER_NAME   string                       = "Public"
+	DEFAULT_PRIVATE_SUBNET_TIER_NAME  string                       = "Private"
+	DEFAULT_ISOLATED_SUBNET_TIER_NAME string                       = "Isolated"
+)
+
+// ManagedVpcProps configures a managed VPC. NAT_MODE_INSTANCE requires NatMachineImage, an image
+// that is already set up to forward traffic (e.g. fck-nat), the NAT instances accept traffic from the VPC CIDR only.
+type ManagedVpcProps struct {
+	Name                   string
+	Cidr                   string
+	MaxAzs                 float64
+	PublicSubnets          SubnetTierProps
+	PrivateSubnets         SubnetTierProps
+	IsolatedSubnets        SubnetTierProps
+	NatGateways            float64
+	NatMode                NatMode
+	NatInstanceClass       ec2.InstanceClass
+	NatInstanceSize        ec2.InstanceSize
+	NatMachineImage        ec2.IMachineImage
+	IsFlowLogEnabled       bool
+	FlowLog                FlowLogProps
+	IsDnsHostnamesDisabled bool
+}
+
+type SubnetTierProps struct {
+	IsEnabled bool
+	Name      string
+	CidrMask  float64
+}
+
+type FlowLogProps struct {
+	Destination      FlowLogDestination
+	TrafficType      ec2.FlowLogTrafficType
+	LogRetention     cloudwatchlogs.RetentionDays
+	BucketArn        string
+	BucketKeyPrefix  string
+	IsRetainOnDelete bool
+}
+
+type Vpc interface {
+	constructs.Construct
+	Vpc() ec2.IVpc
+	FlowLog() ec2.FlowLog
+}
+
+type vpc struct {
+	constructs.Construct
+	vpc     ec2.Vpc
+	flowLog ec2.FlowLog
+}
+
+func (v *vpc) Vpc() ec2.IVpc {
+	return v.vpc
+}
+
+func (v *vpc) FlowLog() ec2.FlowLog {
+	return v.flowLog
+}
+
+func NewVpc(scope constructs.Construct, id *string, props *ManagedVpcProps) Vpc {
+	this := constructs.NewConstruct(scope, id)
+
+	subnetConfiguration := createSubnetConfiguration(props)
+
+	cidr := props.Cidr
+	if cidr == "" {
+		cidr = DEFAULT_VPC_CIDR
+	}
+	maxAzs := props.MaxAzs
+	if maxAzs == 0 {
+		maxAzs = DEFAULT_VPC_MAX_AZS
+	}
+
+	var natGateways float64 = 0
+	var natProvider ec2.NatProvider = nil
+	if props.PrivateSubnets.IsEnabled {
+		natGateways = props.NatGateways
+		if natGateways == 0 {
+			natGateways = 1
+		}
+		natProvider = createNatProvider(props)
+	}
+
+	v := ec2.NewVpc(this, jsii.String("Vpc"), &ec2.VpcProps{
+		VpcName:             jsii.String(props.Name),
+		IpAddresses:         ec2.IpAddresses_Cidr(jsii.String(cidr)),
+		MaxAzs:              jsii.Number(maxAzs),
+		SubnetConfiguration: &subnetConfiguration,
+		NatGateways:         jsii.Number(natGateways),
+		NatGatewayProvider:  natProvider,
+		EnableDnsHostnames:  jsii.Bool(!props.IsDnsHostnamesDisabled),
+		EnableDnsSupport:    jsii.Bool(true),
+	})
+	if natInstances, ok := natProvider.(ec2.NatInstanceProvider); ok {
+		natInstances.Connections().AllowFrom(ec2.Peer_Ipv4(v.VpcCidrBlock()), ec2.Port_AllTraffic(), jsii.String("Traffic from the VPC"))
+	}
+
+	var flowLog ec2.FlowLog = nil
+	if props.IsFlowLogEnabled {
+		flowLog = createFlowLog(this, jsii.String("FlowLog"), &props.FlowLog, v)
+	}
+
+	return &vpc{this, v, flowLog}
+}
+
+func createSubnetConfiguration(props *ManagedVpcProps) []*ec2.SubnetConfiguration {
+	if !props.PublicSubnets.IsEnabled && !props.PrivateSubnets.IsEnabled && !props.IsolatedSubnets.IsEnabled {
+		panic("vpc " + props.Name + " requires at least one enabled subnet tier")
+	}
+	if props.PrivateSubnets.IsEnabled && !props.PublicSubnets.IsEnabled {
+		panic("vpc " + props.Name + " requires public subnets to host the NAT for its private subnets")
+	}
+
+	subnetConfiguration := []*ec2.SubnetConfiguration{}
+	if props.PublicSubnets.IsEnabled {
+		subnetConfiguration = append(subnetConfiguration, createSubnetTier(&props.PublicSubnets, DEFAULT_PUBLIC_SUBNET_TIER_NAME, ec2.SubnetType_PUBLIC))
+	}
+	if props.PrivateSubnets.IsEnabled {
+		subnetConfiguration = append(subnetConfiguration, createSubnetTier(&props.PrivateSubnets, DEFAULT_PRIVATE_SUBNET_TIER_NAME, ec2.SubnetType_PRIVATE_WITH_EGRESS))
+	}
+	if props.IsolatedSubnets.IsEnabled {
+		subnetConfiguration = append(subnetConfiguration, createSubnetTier(&props.IsolatedSubnets, DEFAULT_ISOLATED_SUBNET_TIER_NAME, ec2.SubnetType_PRIVATE_ISOLATED))
+	}
+	return subnetConfiguration
+}
+
+func createSubnetTier(props *SubnetTierProps, defaultName string, subnetType ec2.SubnetType) *ec2.SubnetConfiguration {
+	name := props.Name
+	if name == "" {
+		name = defaultName
+	}
+	cidrMask := props.CidrMask
+	if cidrMask == 0 {
+		cidrMask = DEFAULT_SUBNET_CIDR_MASK
+	}
+	return &ec2.SubnetConfiguration{
+		Name:       jsii.String(name),
+		SubnetType: subnetType,
+		CidrMask:   jsii.Number(cidrMask),
+	}
+}
+
+func createNatProvider(props *ManagedVpcProps) ec2.NatProvider {
+	switch props.NatMode {
+	case "", NAT_MODE_GATEWAY:
+		return ec2.NatProvider_Gateway(&ec2.NatGatewayProps{})
+	case NAT_MODE_INSTANCE:
+		instanceClass := props.NatInstanceClass
+		if instanceClass == "" {
+			instanceClass = DEFAULT_NAT_INSTANCE_CLASS
+		}
+		instanceSize := props.NatInstanceSize
+		if instanceSize == "" {
+			instanceSize = DEFAULT_NAT_INSTANCE_SIZE
+		}
+		if props.NatMachineImage == nil {
+			panic("vpc " + props.Name + " requires NatMachineImage for NAT instances")
+		}
+		return ec2.NatProvider_Instance(&ec2.NatInstanceProps{
+			InstanceType:          ec2.InstanceType_Of(instanceClass, instanceSize),
+			MachineImage:          props.NatMachineImage,
+			DefaultAllowedTraffic: ec2.NatTrafficDirection_OUTBOUND_ONLY,
+		})
+	default:
+		panic(fmt.Sprintf("invalid NAT mode %q for vpc %s", props.NatMode, props.Name))
+	}
+}
+
+func createFlowLog(scope constructs.Construct, id *string, props *FlowLogProps, v ec2.IVpc) ec2.FlowLog {
+	removalPolicy := awscdk.RemovalPolicy_DESTROY
+	if props.IsRetainOnDelete {
+		removalPolicy = awscdk.RemovalPolicy_RETAIN
+	}
+
+	var destination ec2.FlowLogDestination
+	switch props.Destination {
+	case "", FLOW_LOG_DESTINATION_CLOUDWATCH_LOGS:
+		retention := props.LogRetention
+		if retention == "" {
+			retention = DEFAULT_FLOW_LOG_RETENTION
+		}
+		logGroup := cloudwatchlogs.NewLogGroup(scope, jsii.String("FlowLogGroup"), &cloudwatchlogs.LogGroupProps{
+			Retention:     retention,
+			RemovalPolicy: removalPolicy,
+		})
+		destination = ec2.FlowLogDestination_ToCloudWatchLogs(logGroup, nil)
+	case FLOW_LOG_DESTINATION_S3:
+		var bucket s3.IBucket
+		if props.BucketArn != "" {
+			bucket = s3.Bucket_FromBucketArn(scope, jsii.String("FlowLogBucket"), jsii.String(props.BucketArn))
+		} else {
+			bucket = s3.NewBucket(scope, jsii.String("FlowLogBucket"), &s3.BucketProps{
+				Encryption:        s3.BucketEncryption_S3_MANAGED,
+				BlockPublicAccess: s3.BlockPublicAccess_BLOCK_ALL(),
+				EnforceSSL:        jsii.Bool(true),
+				RemovalPolicy:     removalPolicy,
+			})
+		}
+		destination = ec2.FlowLogDestination_ToS3(bucket, optionalString(props.BucketKeyPrefix), nil)
+	default:
+		panic(fmt.Sprintf("invalid flow log destination %q", props.Destination))
+	}
+
+	trafficType := props.TrafficType
+	if trafficType == "" {
+		trafficType = DEFAULT_FLOW_LOG_TRAFFIC_TYPE
+	}
+
+	flowLog := ec2.NewFlowLog(scope, id, &ec2.FlowLogProps{
+		ResourceType: ec2.FlowLogResourceType_FromVpc(v),
+		Destination:  destination,
+		TrafficType:  trafficType,
+	})
+	return flowLog
+}
+
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return jsii.String(s)
+}